@@ -0,0 +1,100 @@
+package component
+
+import (
+	"encoding/xml"
+	"io"
+	"io/ioutil"
+	"net/http"
+)
+
+//授权事件类型
+const (
+	InfoTypeVerifyTicket     = "component_verify_ticket"
+	InfoTypeAuthorized       = "authorized"
+	InfoTypeUpdateAuthorized = "updateauthorized"
+	InfoTypeUnauthorized     = "unauthorized"
+	InfoTypeFastRegister     = "notify_third_fasteregister"
+)
+
+//maxEventBody 授权事件请求体最大长度
+const maxEventBody = 1 << 20
+
+//AuthEventFunc 授权事件回调，返回错误时不回复success，微信服务器会重试推送
+type AuthEventFunc func(event *XCEvent) error
+
+//AuthEventHandler 授权事件接收URL的http.Handler
+//校验签名并解密后更新component_verify_ticket，按InfoType分发到对应回调，处理成功后回复success
+type AuthEventHandler struct {
+	Component          *Component
+	OnVerifyTicket     AuthEventFunc
+	OnAuthorized       AuthEventFunc
+	OnUpdateAuthorized AuthEventFunc
+	OnUnauthorized     AuthEventFunc
+	OnFastRegister     AuthEventFunc
+}
+
+//NewAuthEventHandler 新建授权事件处理器
+func (c *Component) NewAuthEventHandler() *AuthEventHandler {
+	return &AuthEventHandler{Component: c}
+}
+
+//ServeHTTP 处理授权事件推送
+func (h *AuthEventHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, maxEventBody+1))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(body) > maxEventBody {
+		http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+		return
+	}
+	var msg XEncryptMsg
+	err = xml.Unmarshal(body, &msg)
+	if err != nil {
+		http.Error(w, "xml Unmarshal Error", http.StatusBadRequest)
+		return
+	}
+
+	q := r.URL.Query()
+	signature, timestamp, nonce := q.Get("msg_signature"), q.Get("timestamp"), q.Get("nonce")
+	event, err := h.Component.AuthEventTicket(msg.Encrypt, signature, timestamp, nonce)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = h.dispatch(event)
+	if err != nil {
+		//释放nonce，微信服务器以相同参数重试时可以再次处理
+		h.Component.ReleaseNonce(signature, timestamp, nonce)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	io.WriteString(w, "success")
+}
+
+//dispatch 按InfoType调用回调
+func (h *AuthEventHandler) dispatch(event *XCEvent) error {
+	var fn AuthEventFunc
+	switch event.InfoType {
+	case InfoTypeVerifyTicket:
+		fn = h.OnVerifyTicket
+	case InfoTypeAuthorized:
+		fn = h.OnAuthorized
+	case InfoTypeUpdateAuthorized:
+		fn = h.OnUpdateAuthorized
+	case InfoTypeUnauthorized:
+		fn = h.OnUnauthorized
+	case InfoTypeFastRegister:
+		fn = h.OnFastRegister
+	}
+	if fn == nil {
+		return nil
+	}
+	return fn(event)
+}
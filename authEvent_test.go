@@ -0,0 +1,99 @@
+package component
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAuthEventHandler(t *testing.T) {
+	c := newTestComponent(t)
+	h := c.NewAuthEventHandler()
+	var got []string
+	record := func(event *XCEvent) error {
+		got = append(got, event.InfoType+":"+event.AuthorizerAppid)
+		return nil
+	}
+	h.OnVerifyTicket = record
+	h.OnAuthorized = record
+	h.OnUpdateAuthorized = record
+	unauthorizedErr := errors.New("remove failed")
+	h.OnUnauthorized = func(event *XCEvent) error {
+		record(event)
+		return unauthorizedErr
+	}
+	h.OnFastRegister = record
+
+	seq := 0
+	var target, sent string
+	send := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("POST", target, strings.NewReader(sent)))
+		return rec
+	}
+	post := func(body string) *httptest.ResponseRecorder {
+		encrypt, err := c.MsgEncrypt(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		//每次推送使用不同的nonce，避免被判定为重放
+		seq++
+		nonce := "nonce" + strconv.Itoa(seq)
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		q := url.Values{}
+		q.Set("timestamp", timestamp)
+		q.Set("nonce", nonce)
+		q.Set("msg_signature", GetSignature(encrypt, c.ComponentToken, timestamp, nonce))
+		target = "/auth/event?" + q.Encode()
+		sent = "<xml><AppId>" + c.ComponentAppid + "</AppId><Encrypt>" + encrypt + "</Encrypt></xml>"
+		return send()
+	}
+	event := func(infoType, extra string) string {
+		return "<xml><AppId>" + c.ComponentAppid + "</AppId><CreateTime>1413192605</CreateTime><InfoType>" +
+			infoType + "</InfoType><AuthorizerAppid>wxauth</AuthorizerAppid>" + extra + "</xml>"
+	}
+
+	for _, infoType := range []string{InfoTypeVerifyTicket, InfoTypeAuthorized, InfoTypeUpdateAuthorized, InfoTypeFastRegister, "unknown"} {
+		rec := post(event(infoType, "<ComponentVerifyTicket>ticket@@@new</ComponentVerifyTicket>"))
+		if rec.Code != http.StatusOK || rec.Body.String() != "success" {
+			t.Errorf("%s: response = %d %s", infoType, rec.Code, rec.Body)
+		}
+	}
+	if ticket, _ := c.verifyTicket(); ticket != "ticket@@@new" {
+		t.Errorf("verify ticket = %s", ticket)
+	}
+	//回调返回错误时不回复success，微信服务器会重试
+	if rec := post(event(InfoTypeUnauthorized, "")); rec.Code != http.StatusInternalServerError {
+		t.Errorf("unauthorized with error: status = %d", rec.Code)
+	}
+	//重试的推送与失败的推送参数相同，回调成功后回复success，再次重放则被拒绝
+	unauthorizedErr = nil
+	if rec := send(); rec.Code != http.StatusOK || rec.Body.String() != "success" {
+		t.Errorf("retried unauthorized: response = %d %s", rec.Code, rec.Body)
+	}
+	if rec := send(); rec.Code != http.StatusBadRequest {
+		t.Errorf("replayed unauthorized: status = %d", rec.Code)
+	}
+	want := []string{
+		InfoTypeVerifyTicket + ":wxauth", InfoTypeAuthorized + ":wxauth", InfoTypeUpdateAuthorized + ":wxauth",
+		InfoTypeFastRegister + ":wxauth", InfoTypeUnauthorized + ":wxauth", InfoTypeUnauthorized + ":wxauth",
+	}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("dispatched = %v, want %v", got, want)
+	}
+
+	rec := httptest.NewRecorder()
+	big := "<xml><Encrypt>" + strings.Repeat("a", maxEventBody) + "</Encrypt></xml>"
+	h.ServeHTTP(rec, httptest.NewRequest("POST", "/auth/event", strings.NewReader(big)))
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("oversized body status = %d", rec.Code)
+	}
+	if len(got) != len(want) {
+		t.Error("oversized body dispatched")
+	}
+}
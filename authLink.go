@@ -0,0 +1,127 @@
+package component
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+//AuthType 授权帐号类型
+type AuthType int
+
+//授权帐号类型
+const (
+	AuthTypeMP          AuthType = 1 //仅展示公众号
+	AuthTypeMiniProgram AuthType = 2 //仅展示小程序
+	AuthTypeAll         AuthType = 3 //公众号和小程序都展示
+)
+
+//授权页地址
+const (
+	URLComponentLoginPage = "https://mp.weixin.qq.com/cgi-bin/componentloginpage"
+	URLBindComponent      = "https://mp.weixin.qq.com/safe/bindcomponent"
+)
+
+//errNoAuthCode 回调缺少auth_code
+var errNoAuthCode = errors.New("auth_code is empty")
+
+//AuthLink 授权链接
+type AuthLink struct {
+	PreAuthCode string
+	ExpiresIn   int
+	//PC PC端授权页，需在网页中跳转，扫码授权
+	PC string
+	//Mobile 移动端授权页，需在微信客户端中打开
+	Mobile string
+}
+
+//GetAuthLink 获取新的预授权码并生成PC扫码及移动端授权链接
+//bizAppid不为空时仅允许该帐号授权
+func (c *Component) GetAuthLink(redirectURI string, authType AuthType, bizAppid string) (link *AuthLink, err error) {
+	return c.GetAuthLinkContext(context.Background(), redirectURI, authType, bizAppid)
+}
+
+//GetAuthLinkContext 同GetAuthLink，使用ctx控制请求的取消和超时
+func (c *Component) GetAuthLinkContext(ctx context.Context, redirectURI string, authType AuthType, bizAppid string) (link *AuthLink, err error) {
+	code, err := c.GetPreAuthCodeContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	params := url.Values{}
+	params.Set("component_appid", c.ComponentAppid)
+	params.Set("pre_auth_code", code.PreAuthCode)
+	params.Set("redirect_uri", redirectURI)
+	if authType != 0 {
+		params.Set("auth_type", strconv.Itoa(int(authType)))
+	}
+	if bizAppid != "" {
+		params.Set("biz_appid", bizAppid)
+	}
+	link = &AuthLink{
+		PreAuthCode: code.PreAuthCode,
+		ExpiresIn:   code.ExpiresIn,
+		PC:          URLComponentLoginPage + "?" + params.Encode(),
+	}
+
+	params.Set("action", "bindcomponent")
+	params.Set("no_scan", "1")
+	link.Mobile = URLBindComponent + "?" + params.Encode() + "#wechat_redirect"
+	return link, nil
+}
+
+//AuthRedirectHandler 授权完成后回调redirect_uri的http.Handler
+//取出auth_code换取授权方令牌，加入注册表后调用OnAuthorized
+type AuthRedirectHandler struct {
+	Component *Component
+	//Registry 保存授权方，为空时仅写入令牌存储
+	Registry *Registry
+	//OnAuthorized 授权成功后输出页面，为空时输出success
+	OnAuthorized func(w http.ResponseWriter, r *http.Request, a *Authorizer)
+	//OnError 授权失败时输出页面，为空时返回错误状态码
+	OnError func(w http.ResponseWriter, r *http.Request, err error)
+}
+
+//NewAuthRedirectHandler 新建授权回调处理器
+func (c *Component) NewAuthRedirectHandler(registry *Registry) *AuthRedirectHandler {
+	return &AuthRedirectHandler{
+		Component: c,
+		Registry:  registry,
+	}
+}
+
+//ServeHTTP 处理授权回调
+func (h *AuthRedirectHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	code := r.URL.Query().Get("auth_code")
+	if code == "" {
+		h.error(w, r, errNoAuthCode, http.StatusBadRequest)
+		return
+	}
+	a, err := h.Component.QueryAuthContext(r.Context(), code)
+	if err != nil {
+		h.error(w, r, err, http.StatusBadGateway)
+		return
+	}
+	if h.Registry != nil {
+		err = h.Registry.Add(a)
+		if err != nil {
+			h.error(w, r, err, http.StatusInternalServerError)
+			return
+		}
+	}
+	if h.OnAuthorized != nil {
+		h.OnAuthorized(w, r, a)
+		return
+	}
+	io.WriteString(w, "success")
+}
+
+func (h *AuthRedirectHandler) error(w http.ResponseWriter, r *http.Request, err error, code int) {
+	if h.OnError != nil {
+		h.OnError(w, r, err)
+		return
+	}
+	http.Error(w, err.Error(), code)
+}
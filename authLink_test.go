@@ -0,0 +1,90 @@
+package component_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/wei193/component"
+	"github.com/wei193/component/wechattest"
+)
+
+func TestGetAuthLink(t *testing.T) {
+	srv := wechattest.NewServer()
+	defer srv.Close()
+	c, err := srv.Component(wechattest.ComponentAppid, wechattest.Appsecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	redirect := "https://example.com/auth?from=link&id=1"
+	link, err := c.GetAuthLink(redirect, component.AuthTypeMiniProgram, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	escaped := "redirect_uri=" + url.QueryEscape(redirect)
+
+	if !strings.HasPrefix(link.PC, component.URLComponentLoginPage+"?") || !strings.Contains(link.PC, escaped) {
+		t.Errorf("PC = %s", link.PC)
+	}
+	pc, _ := url.Parse(link.PC)
+	q := pc.Query()
+	if q.Get("component_appid") != wechattest.ComponentAppid || q.Get("pre_auth_code") != "PRE_AUTH_CODE" ||
+		q.Get("auth_type") != "2" || q.Get("redirect_uri") != redirect || q.Get("action") != "" {
+		t.Errorf("PC query = %v", q)
+	}
+
+	if !strings.HasPrefix(link.Mobile, component.URLBindComponent+"?") || !strings.HasSuffix(link.Mobile, "#wechat_redirect") ||
+		!strings.Contains(link.Mobile, escaped) {
+		t.Errorf("Mobile = %s", link.Mobile)
+	}
+	mobile, _ := url.Parse(link.Mobile)
+	q = mobile.Query()
+	if q.Get("action") != "bindcomponent" || q.Get("no_scan") != "1" || q.Get("redirect_uri") != redirect ||
+		mobile.Fragment != "wechat_redirect" {
+		t.Errorf("Mobile query = %v, fragment = %s", q, mobile.Fragment)
+	}
+}
+
+func TestAuthRedirectHandler(t *testing.T) {
+	srv := wechattest.NewServer()
+	defer srv.Close()
+	c, err := srv.Component(wechattest.ComponentAppid, wechattest.Appsecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	registry := c.NewRegistry(component.NewMemoryAuthorizerStore())
+	h := c.NewAuthRedirectHandler(registry)
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/auth?auth_code=AUTH_CODE&expires_in=3600", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "success" {
+		t.Fatalf("response = %d %s", w.Code, w.Body)
+	}
+	if n := srv.Calls("/cgi-bin/component/api_query_auth"); n != 1 {
+		t.Errorf("query auth calls = %d", n)
+	}
+	reqs := srv.Requests()
+	if !strings.Contains(string(reqs[len(reqs)-1].Body), `"authorization_code":"AUTH_CODE"`) {
+		t.Errorf("query auth body = %s", reqs[len(reqs)-1].Body)
+	}
+	if _, err = registry.Get(wechattest.Appid); err != nil {
+		t.Errorf("authorizer not added: %v", err)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/auth", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("missing auth_code status = %d", w.Code)
+	}
+
+	srv.Inject("/cgi-bin/component/api_query_auth", wechattest.Fault{Errcode: 61010, Errmsg: "code is expired"})
+	var handled error
+	h.OnError = func(w http.ResponseWriter, r *http.Request, err error) { handled = err }
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/auth?auth_code=EXPIRED", nil))
+	if handled == nil {
+		t.Error("OnError not called for expired auth_code")
+	}
+}
@@ -1,10 +1,14 @@
 package component
 
 import (
+	"context"
 	"encoding/json"
-	"log"
+	"errors"
+	"net/url"
+	"sync"
 	"time"
 
+	"github.com/wei193/component/common"
 	"github.com/wei193/component/wechat"
 )
 
@@ -16,6 +20,15 @@ type Authorizer struct {
 	// AuthorizerAccessToken  string
 	// AccessTokenExpires     int64
 	AuthorizerRefreshToken string
+	//Scopes 授权给第三方平台的权限集，为空表示未知
+	Scopes ScopeSet
+	//UserName 授权方原始ID（gh_开头），用于校验消息的接收方，为空时解密消息前通过GetAuthorizerInfo获取
+	UserName string
+
+	//refreshLock 保护刷新令牌时对AuthorizerRefreshToken的读写
+	refreshLock sync.Mutex
+	//infoLock 保护GetAuthorizerInfo对UserName的写入
+	infoLock sync.Mutex
 }
 
 //JAuthorizer 授权信息
@@ -99,13 +112,22 @@ func (c *Component) NewAuthorizer(appid, accesstoken string, tokenexpires int64,
 			Appid:              appid,
 			AccessToken:        accesstoken,
 			AccessTokenExpires: tokenexpires,
+			Store:              c.Store,
+			Locker:             c.Locker,
+			Client:             c.Client,
 		},
 	}
+	authorizer.TokenFunc = authorizer.refreshAccessToken
 	return
 }
 
 //GetAuthorizerInfo 获取授权详细信息
 func (a *Authorizer) GetAuthorizerInfo() (authorizer *JAuthorizer, err error) {
+	return a.GetAuthorizerInfoContext(context.Background())
+}
+
+//GetAuthorizerInfoContext 同GetAuthorizerInfo，使用ctx控制请求的取消和超时
+func (a *Authorizer) GetAuthorizerInfoContext(ctx context.Context) (authorizer *JAuthorizer, err error) {
 	type st struct {
 		ComponentAppid  string `json:"component_appid"`
 		AuthorizerAppid string `json:"authorizer_appid"`
@@ -114,27 +136,92 @@ func (a *Authorizer) GetAuthorizerInfo() (authorizer *JAuthorizer, err error) {
 		ComponentAppid:  a.Component.ComponentAppid,
 		AuthorizerAppid: a.Wechat.Appid,
 	}
-	req, err := createRequset("https://api.weixin.qq.com/cgi-bin/component/api_get_authorizer_info?component_access_token="+a.Component.ComponentAccessToken,
+	accessToken, err := a.Component.TokenContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	req, err := createRequset(ctx, "https://api.weixin.qq.com/cgi-bin/component/api_get_authorizer_info?component_access_token="+accessToken,
 		"POST", nil, d)
 	if err != nil {
 		return nil, err
 	}
-	res, err := requsetJosn(req)
+	res, err := a.Component.requsetJosn(req)
 	if err != nil {
 		return nil, err
 	}
-	log.Println(string(res))
 
 	var auth JAuthorizer
 	err = json.Unmarshal(res, &auth)
 	if err != nil {
 		return nil, err
 	}
+	a.Scopes = NewScopeSet(auth.AuthorizationInfo.FuncInfo)
+	a.infoLock.Lock()
+	a.UserName = auth.AuthorizerInfo.UserName
+	a.infoLock.Unlock()
 	return &auth, nil
 }
 
+//ErrReceiverUnknown 未能获取授权方原始ID，无法校验消息的接收方
+var ErrReceiverUnknown = errors.New("authorizer user_name is unknown")
+
+//userName 授权方原始ID，UserName为空时调用GetAuthorizerInfo获取
+func (a *Authorizer) userName(ctx context.Context) (string, error) {
+	a.infoLock.Lock()
+	userName := a.UserName
+	a.infoLock.Unlock()
+	if userName != "" {
+		return userName, nil
+	}
+	auth, err := a.GetAuthorizerInfoContext(ctx)
+	if err != nil {
+		return "", err
+	}
+	if auth.AuthorizerInfo.UserName == "" {
+		return "", ErrReceiverUnknown
+	}
+	return auth.AuthorizerInfo.UserName, nil
+}
+
 //GetAuthorizerAccessToken 获取Authorizer AccessToken
+//获取刷新锁后重新读取令牌存储，避免多个实例重复刷新导致authorizer_refresh_token失效
 func (a *Authorizer) GetAuthorizerAccessToken() (token *JAuthorizerAccessToken, err error) {
+	return a.GetAuthorizerAccessTokenContext(context.Background())
+}
+
+//GetAuthorizerAccessTokenContext 同GetAuthorizerAccessToken，使用ctx控制请求的取消和超时
+func (a *Authorizer) GetAuthorizerAccessTokenContext(ctx context.Context) (token *JAuthorizerAccessToken, err error) {
+	_, err = a.RefreshAccessTokenContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	accessToken, expires := a.CurrentAccessToken()
+	return &JAuthorizerAccessToken{
+		AuthorizerAccessToken:  accessToken,
+		ExpiresIn:              int(expires - time.Now().Unix()),
+		AuthorizerRefreshToken: a.RefreshToken(),
+	}, nil
+}
+
+//refreshAccessToken 刷新令牌，作为Wechat的TokenFunc使用
+func (a *Authorizer) refreshAccessToken(ctx context.Context) (token string, expiresIn int, err error) {
+	t, err := a.requsetAuthorizerAccessToken(ctx)
+	if err != nil {
+		return "", 0, err
+	}
+	return t.AuthorizerAccessToken, t.ExpiresIn, nil
+}
+
+//requsetAuthorizerAccessToken 使用authorizer_refresh_token换取新的令牌
+//新的authorizer_refresh_token会写入令牌存储
+func (a *Authorizer) requsetAuthorizerAccessToken(ctx context.Context) (token *JAuthorizerAccessToken, err error) {
+	refreshToken, _, err := a.Component.loadToken(a.Appid, common.TokenAuthorizerRefresh)
+	if err != nil {
+		return nil, err
+	}
+	if refreshToken != "" {
+		a.setRefreshToken(refreshToken)
+	}
 	type st struct {
 		ComponentAppid         string `json:"component_appid"`
 		AuthorizerAppid        string `json:"authorizer_appid"`
@@ -143,44 +230,95 @@ func (a *Authorizer) GetAuthorizerAccessToken() (token *JAuthorizerAccessToken,
 	d := st{
 		ComponentAppid:         a.Component.ComponentAppid,
 		AuthorizerAppid:        a.Appid,
-		AuthorizerRefreshToken: a.AuthorizerRefreshToken,
+		AuthorizerRefreshToken: a.RefreshToken(),
 	}
-	req, err := createRequset("https://api.weixin.qq.com/cgi-bin/component/api_authorizer_token?component_access_token="+a.Component.ComponentAccessToken,
+	accessToken, err := a.Component.TokenContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	req, err := createRequset(ctx, "https://api.weixin.qq.com/cgi-bin/component/api_authorizer_token?component_access_token="+accessToken,
 		"POST", nil, d)
 	if err != nil {
 		return nil, err
 	}
-	res, err := requsetJosn(req)
+	res, err := a.Component.requsetJosn(req)
 	if err != nil {
 		return nil, err
 	}
-	log.Println(string(res))
 	token = new(JAuthorizerAccessToken)
 	err = json.Unmarshal(res, token)
 	if err != nil {
 		return nil, err
 	}
-	a.AccessToken = token.AuthorizerAccessToken
-	a.AuthorizerRefreshToken = token.AuthorizerRefreshToken
-	a.AccessTokenExpires = time.Now().Unix() + int64(token.ExpiresIn)
+	if token.AuthorizerRefreshToken != "" {
+		a.setRefreshToken(token.AuthorizerRefreshToken)
+	}
+	err = a.Component.saveToken(a.Appid, common.TokenAuthorizerRefresh, a.RefreshToken(), 0)
+	if err != nil {
+		return nil, err
+	}
 	return token, nil
 }
 
+//requsetWithToken 使用授权方access_token发送请求，令牌无效时刷新并重放
+func (a *Authorizer) requsetWithToken(ctx context.Context, surl, method string, p map[string]string, d interface{}) ([]byte, error) {
+	accessToken, err := a.ValidAccessTokenContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	req, err := createRequset(ctx, surl+"?access_token="+url.QueryEscape(accessToken), method, p, d)
+	if err != nil {
+		return nil, err
+	}
+	return a.RequsetJSON(req, 0)
+}
+
+//saveTokens 将当前令牌写入令牌存储
+func (a *Authorizer) saveTokens() error {
+	err := a.Component.saveToken(a.Appid, common.TokenAuthorizerRefresh, a.RefreshToken(), 0)
+	if err != nil {
+		return err
+	}
+	return a.SetAccessToken(a.CurrentAccessToken())
+}
+
+//RefreshToken 当前的authorizer_refresh_token
+func (a *Authorizer) RefreshToken() string {
+	a.refreshLock.Lock()
+	defer a.refreshLock.Unlock()
+	return a.AuthorizerRefreshToken
+}
+
+func (a *Authorizer) setRefreshToken(token string) {
+	a.refreshLock.Lock()
+	defer a.refreshLock.Unlock()
+	a.AuthorizerRefreshToken = token
+}
+
 //CodeToAccessToken 通过code换取access_token
 func (a *Authorizer) CodeToAccessToken(code string) (token *JUserAccessToken, err error) {
+	return a.CodeToAccessTokenContext(context.Background(), code)
+}
+
+//CodeToAccessTokenContext 同CodeToAccessToken，使用ctx控制请求的取消和超时
+func (a *Authorizer) CodeToAccessTokenContext(ctx context.Context, code string) (token *JUserAccessToken, err error) {
+	accessToken, err := a.Component.TokenContext(ctx)
+	if err != nil {
+		return nil, err
+	}
 	param := make(map[string]string)
 	param["appid"] = a.Appid
 	param["code"] = code
 	param["grant_type"] = "authorization_code"
 	param["component_appid"] = a.Component.ComponentAppid
-	param["component_access_token"] = a.Component.ComponentAccessToken
+	param["component_access_token"] = accessToken
 
-	req, err := createRequset("https://api.weixin.qq.com/sns/oauth2/component/access_token",
+	req, err := createRequset(ctx, "https://api.weixin.qq.com/sns/oauth2/component/access_token",
 		"GET", param, nil)
 	if err != nil {
 		return nil, err
 	}
-	res, err := requsetJosn(req)
+	res, err := a.Component.requsetJosn(req)
 	if err != nil {
 		return nil, err
 	}
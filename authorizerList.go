@@ -0,0 +1,181 @@
+package component
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/wei193/component/common"
+)
+
+//authorizerListMax 拉取授权方列表每页最大数量
+const authorizerListMax = 500
+
+//JAuthorizerList 授权方列表
+type JAuthorizerList struct {
+	TotalCount int                   `json:"total_count"`
+	List       []JAuthorizerListItem `json:"list"`
+}
+
+//JAuthorizerListItem 授权方列表项
+type JAuthorizerListItem struct {
+	AuthorizerAppid string `json:"authorizer_appid"`
+	RefreshToken    string `json:"refresh_token"`
+	AuthTime        int64  `json:"auth_time"`
+}
+
+//GetAuthorizerList 拉取已授权的帐号列表，count最大为500
+func (c *Component) GetAuthorizerList(offset, count int) (list *JAuthorizerList, err error) {
+	return c.GetAuthorizerListContext(context.Background(), offset, count)
+}
+
+//GetAuthorizerListContext 同GetAuthorizerList，使用ctx控制请求的取消和超时
+func (c *Component) GetAuthorizerListContext(ctx context.Context, offset, count int) (list *JAuthorizerList, err error) {
+	if count <= 0 || count > authorizerListMax {
+		count = authorizerListMax
+	}
+	type st struct {
+		ComponentAppid string `json:"component_appid"`
+		Offset         int    `json:"offset"`
+		Count          int    `json:"count"`
+	}
+	d := st{
+		ComponentAppid: c.ComponentAppid,
+		Offset:         offset,
+		Count:          count,
+	}
+	accessToken, err := c.TokenContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	req, err := createRequset(ctx, "https://api.weixin.qq.com/cgi-bin/component/api_get_authorizer_list?component_access_token="+accessToken,
+		"POST", nil, d)
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.requsetJosn(req)
+	if err != nil {
+		return nil, err
+	}
+	list = new(JAuthorizerList)
+	err = json.Unmarshal(res, list)
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+//AuthorizerIterator 逐页遍历已授权的帐号
+//
+//	it := c.Authorizers()
+//	for it.Next() {
+//		item := it.Item()
+//	}
+//	err := it.Err()
+type AuthorizerIterator struct {
+	c      *Component
+	ctx    context.Context
+	offset int
+	total  int
+	page   []JAuthorizerListItem
+	index  int
+	item   JAuthorizerListItem
+	done   bool
+	err    error
+}
+
+//Authorizers 遍历已授权的帐号
+func (c *Component) Authorizers() *AuthorizerIterator {
+	return c.AuthorizersContext(context.Background())
+}
+
+//AuthorizersContext 同Authorizers，拉取每一页时使用ctx
+func (c *Component) AuthorizersContext(ctx context.Context) *AuthorizerIterator {
+	return &AuthorizerIterator{c: c, ctx: ctx, total: -1}
+}
+
+//Next 移动到下一个授权方，没有更多或出错时返回false
+func (it *AuthorizerIterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+	if it.index >= len(it.page) {
+		if it.done || (it.total >= 0 && it.offset >= it.total) {
+			return false
+		}
+		list, err := it.c.GetAuthorizerListContext(it.ctx, it.offset, authorizerListMax)
+		if err != nil {
+			it.err = err
+			return false
+		}
+		it.total = list.TotalCount
+		it.page = list.List
+		it.index = 0
+		it.offset += len(list.List)
+		if len(list.List) == 0 {
+			it.done = true
+			return false
+		}
+	}
+	it.item = it.page[it.index]
+	it.index++
+	return true
+}
+
+//Item 当前授权方
+func (it *AuthorizerIterator) Item() JAuthorizerListItem {
+	return it.item
+}
+
+//Err 遍历过程中的错误
+func (it *AuthorizerIterator) Err() error {
+	return it.err
+}
+
+//GetAllAuthorizerList 拉取全部已授权的帐号
+func (c *Component) GetAllAuthorizerList() (items []JAuthorizerListItem, err error) {
+	return c.GetAllAuthorizerListContext(context.Background())
+}
+
+//GetAllAuthorizerListContext 同GetAllAuthorizerList，使用ctx控制请求的取消和超时
+func (c *Component) GetAllAuthorizerListContext(ctx context.Context) (items []JAuthorizerListItem, err error) {
+	it := c.AuthorizersContext(ctx)
+	for it.Next() {
+		items = append(items, it.Item())
+	}
+	return items, it.Err()
+}
+
+//Rebuild 从微信拉取全部已授权的帐号并加入注册表
+//已在注册表中的授权方只更新authorizer_refresh_token，保留其令牌、权限集等信息
+func (r *Registry) Rebuild() error {
+	return r.RebuildContext(context.Background())
+}
+
+//RebuildContext 同Rebuild，使用ctx控制请求的取消和超时
+func (r *Registry) RebuildContext(ctx context.Context) error {
+	it := r.Component.AuthorizersContext(ctx)
+	for it.Next() {
+		item := it.Item()
+		err := r.Component.saveToken(item.AuthorizerAppid, common.TokenAuthorizerRefresh, item.RefreshToken, 0)
+		if err != nil {
+			return err
+		}
+		if a, err := r.Get(item.AuthorizerAppid); err == nil {
+			a.setRefreshToken(item.RefreshToken)
+			err = r.Store.SaveAuthorizer(a.record())
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		a, err := r.Component.NewAuthorizer(item.AuthorizerAppid, "", 0, item.RefreshToken)
+		if err != nil {
+			return err
+		}
+		err = r.Add(a)
+		if err != nil {
+			return err
+		}
+	}
+	return it.Err()
+}
@@ -0,0 +1,124 @@
+package component_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/wei193/component"
+	"github.com/wei193/component/common"
+	"github.com/wei193/component/wechattest"
+)
+
+//handleAuthorizerList 模拟分页返回total个授权方，每页最多pageSize个，第failPage页返回错误
+func handleAuthorizerList(srv *wechattest.Server, total, pageSize, failPage int) {
+	page := 0
+	srv.Handle("/cgi-bin/component/api_get_authorizer_list", func(w http.ResponseWriter, r *http.Request) {
+		page++
+		if page == failPage {
+			w.Write([]byte(`{"errcode":61003,"errmsg":"component is not authorized by this account"}`))
+			return
+		}
+		var req struct {
+			Offset int `json:"offset"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		list := []component.JAuthorizerListItem{}
+		for i := req.Offset; i < req.Offset+pageSize && i < total; i++ {
+			appid := fmt.Sprintf("wxauth%d", i)
+			list = append(list, component.JAuthorizerListItem{AuthorizerAppid: appid, RefreshToken: "REFRESH_" + appid})
+		}
+		json.NewEncoder(w).Encode(component.JAuthorizerList{TotalCount: total, List: list})
+	})
+}
+
+func TestAuthorizerIterator(t *testing.T) {
+	srv := wechattest.NewServer()
+	defer srv.Close()
+	c, err := srv.Component(wechattest.ComponentAppid, wechattest.Appsecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handleAuthorizerList(srv, 5, 2, 0)
+	items, err := c.GetAllAuthorizerList()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 5 || items[0].AuthorizerAppid != "wxauth0" || items[4].AuthorizerAppid != "wxauth4" {
+		t.Errorf("items = %+v", items)
+	}
+	//3页后offset达到total_count，不再拉取空页
+	if n := srv.Calls("/cgi-bin/component/api_get_authorizer_list"); n != 3 {
+		t.Errorf("list calls = %d, want 3", n)
+	}
+
+	//第2页出错时返回已遍历的授权方及错误
+	handleAuthorizerList(srv, 5, 2, 2)
+	it := c.Authorizers()
+	n := 0
+	for it.Next() {
+		n++
+	}
+	if e, ok := common.AsAPIError(it.Err()); !ok || e.Errcode != 61003 || n != 2 {
+		t.Errorf("iterated %d, err = %v", n, it.Err())
+	}
+	if it.Next() {
+		t.Error("Next after error returned true")
+	}
+}
+
+func TestRegistryRebuild(t *testing.T) {
+	srv := wechattest.NewServer()
+	defer srv.Close()
+	c, err := srv.Component(wechattest.ComponentAppid, wechattest.Appsecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handleAuthorizerList(srv, 3, 2, 0)
+
+	store := component.NewMemoryAuthorizerStore()
+	r := c.NewRegistry(store)
+	token := srv.IssueToken()
+	existing, err := c.NewAuthorizer("wxauth1", token, 1<<62, "OLD_REFRESH")
+	if err != nil {
+		t.Fatal(err)
+	}
+	existing.Scopes = component.ScopeSet{component.ScopeMessage: true}
+	existing.UserName = "gh_1"
+	if err = r.Add(existing); err != nil {
+		t.Fatal(err)
+	}
+	if err = r.Rebuild(); err != nil {
+		t.Fatal(err)
+	}
+	a, err := r.Get("wxauth2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.RefreshToken() != "REFRESH_wxauth2" {
+		t.Errorf("refresh token = %s", a.RefreshToken())
+	}
+	records, err := store.LoadAuthorizers()
+	if err != nil || len(records) != 3 {
+		t.Errorf("records = %+v, %v", records, err)
+	}
+
+	//已存在的授权方保持同一对象，只更新authorizer_refresh_token
+	a, err = r.Get("wxauth1")
+	if err != nil || a != existing {
+		t.Fatalf("existing authorizer replaced: %p, %v", a, err)
+	}
+	if a.RefreshToken() != "REFRESH_wxauth1" || a.UserName != "gh_1" || !a.Scopes.Has(component.ScopeMessage) {
+		t.Errorf("existing authorizer = %+v", a)
+	}
+	if current, _ := a.CurrentAccessToken(); current != token {
+		t.Errorf("access token = %s, want %s", current, token)
+	}
+	for _, record := range records {
+		if record.Appid == "wxauth1" && (record.AuthorizerRefreshToken != "REFRESH_wxauth1" || record.AccessToken != token) {
+			t.Errorf("saved record = %+v", record)
+		}
+	}
+}
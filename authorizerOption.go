@@ -0,0 +1,105 @@
+package component
+
+import (
+	"context"
+	"encoding/json"
+)
+
+//AuthorizerOption 授权方选项名称
+type AuthorizerOption string
+
+//授权方选项名称
+const (
+	OptionLocationReport  AuthorizerOption = "location_report"
+	OptionVoiceRecognize  AuthorizerOption = "voice_recognize"
+	OptionCustomerService AuthorizerOption = "customer_service"
+)
+
+//OptionValue 授权方选项值
+type OptionValue string
+
+//授权方选项值
+const (
+	//地理位置上报选项
+	LocationReportOff     OptionValue = "0"
+	LocationReportSession OptionValue = "1"
+	LocationReportEvery5s OptionValue = "2"
+
+	//语音识别、多客服选项
+	OptionOff OptionValue = "0"
+	OptionOn  OptionValue = "1"
+)
+
+//JAuthorizerOption 授权方选项
+type JAuthorizerOption struct {
+	AuthorizerAppid string           `json:"authorizer_appid"`
+	OptionName      AuthorizerOption `json:"option_name"`
+	OptionValue     OptionValue      `json:"option_value"`
+}
+
+//GetAuthorizerOption 获取授权方选项信息
+func (a *Authorizer) GetAuthorizerOption(name AuthorizerOption) (value OptionValue, err error) {
+	return a.GetAuthorizerOptionContext(context.Background(), name)
+}
+
+//GetAuthorizerOptionContext 同GetAuthorizerOption，使用ctx控制请求的取消和超时
+func (a *Authorizer) GetAuthorizerOptionContext(ctx context.Context, name AuthorizerOption) (value OptionValue, err error) {
+	type st struct {
+		ComponentAppid  string           `json:"component_appid"`
+		AuthorizerAppid string           `json:"authorizer_appid"`
+		OptionName      AuthorizerOption `json:"option_name"`
+	}
+	d := st{
+		ComponentAppid:  a.Component.ComponentAppid,
+		AuthorizerAppid: a.Appid,
+		OptionName:      name,
+	}
+	accessToken, err := a.Component.TokenContext(ctx)
+	if err != nil {
+		return "", err
+	}
+	req, err := createRequset(ctx, "https://api.weixin.qq.com/cgi-bin/component/api_get_authorizer_option?component_access_token="+accessToken,
+		"POST", nil, d)
+	if err != nil {
+		return "", err
+	}
+	res, err := a.Component.requsetJosn(req)
+	if err != nil {
+		return "", err
+	}
+	var option JAuthorizerOption
+	err = json.Unmarshal(res, &option)
+	if err != nil {
+		return "", err
+	}
+	return option.OptionValue, nil
+}
+
+//SetAuthorizerOption 设置授权方选项信息
+func (a *Authorizer) SetAuthorizerOption(name AuthorizerOption, value OptionValue) (err error) {
+	return a.SetAuthorizerOptionContext(context.Background(), name, value)
+}
+
+//SetAuthorizerOptionContext 同SetAuthorizerOption，使用ctx控制请求的取消和超时
+func (a *Authorizer) SetAuthorizerOptionContext(ctx context.Context, name AuthorizerOption, value OptionValue) (err error) {
+	d := JAuthorizerOption{
+		AuthorizerAppid: a.Appid,
+		OptionName:      name,
+		OptionValue:     value,
+	}
+	type st struct {
+		ComponentAppid string `json:"component_appid"`
+		JAuthorizerOption
+	}
+	accessToken, err := a.Component.TokenContext(ctx)
+	if err != nil {
+		return err
+	}
+	req, err := createRequset(ctx, "https://api.weixin.qq.com/cgi-bin/component/api_set_authorizer_option?component_access_token="+accessToken,
+		"POST", nil, st{a.Component.ComponentAppid, d})
+	if err != nil {
+		return err
+	}
+	_, err = a.Component.requsetJosn(req)
+	return err
+}
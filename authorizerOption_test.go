@@ -0,0 +1,58 @@
+package component_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/wei193/component"
+	"github.com/wei193/component/wechattest"
+)
+
+func TestAuthorizerOption(t *testing.T) {
+	srv := wechattest.NewServer()
+	defer srv.Close()
+	c, err := srv.Component(wechattest.ComponentAppid, wechattest.Appsecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := c.NewAuthorizer("wxauth", srv.IssueToken(), 1<<62, "REFRESH_wxauth")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = a.SetAuthorizerOption(component.OptionVoiceRecognize, component.OptionOn); err != nil {
+		t.Fatal(err)
+	}
+	reqs := srv.Requests()
+	last := reqs[len(reqs)-1]
+	var body map[string]string
+	if err = json.Unmarshal(last.Body, &body); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"component_appid":  wechattest.ComponentAppid,
+		"authorizer_appid": "wxauth",
+		"option_name":      "voice_recognize",
+		"option_value":     "1",
+	}
+	for k, v := range want {
+		if body[k] != v {
+			t.Errorf("%s %s = %q, want %q", last.Path, k, body[k], v)
+		}
+	}
+
+	value, err := a.GetAuthorizerOption(component.OptionVoiceRecognize)
+	if err != nil || value != component.OptionOn {
+		t.Errorf("GetAuthorizerOption = %q, %v", value, err)
+	}
+	srv.SetResponse("/cgi-bin/component/api_get_authorizer_option",
+		`{"authorizer_appid":"wxauth","option_name":"location_report","option_value":"2"}`)
+	value, err = a.GetAuthorizerOption(component.OptionLocationReport)
+	if err != nil || value != component.LocationReportEvery5s {
+		t.Errorf("GetAuthorizerOption = %q, %v", value, err)
+	}
+	reqs = srv.Requests()
+	if err = json.Unmarshal(reqs[len(reqs)-1].Body, &body); err != nil || body["option_name"] != "location_report" {
+		t.Errorf("request body = %s", reqs[len(reqs)-1].Body)
+	}
+}
@@ -0,0 +1,127 @@
+package component
+
+import (
+	"sync"
+
+	"github.com/wei193/component/common"
+)
+
+//AuthorizerRecord 授权方持久化信息
+type AuthorizerRecord struct {
+	Appid                  string  `json:"appid"`
+	AuthorizerRefreshToken string  `json:"authorizer_refresh_token"`
+	AccessToken            string  `json:"access_token"`
+	AccessTokenExpires     int64   `json:"access_token_expires"`
+	Scopes                 []Scope `json:"scopes,omitempty"`
+	UserName               string  `json:"user_name,omitempty"`
+}
+
+//AuthorizerStore 授权方存储
+type AuthorizerStore interface {
+	LoadAuthorizers() ([]AuthorizerRecord, error)
+	SaveAuthorizer(record AuthorizerRecord) error
+	DeleteAuthorizer(appid string) error
+}
+
+//MemoryAuthorizerStore 内存授权方存储
+type MemoryAuthorizerStore struct {
+	lock    sync.RWMutex
+	records map[string]AuthorizerRecord
+}
+
+//NewMemoryAuthorizerStore 新建内存授权方存储
+func NewMemoryAuthorizerStore() *MemoryAuthorizerStore {
+	return &MemoryAuthorizerStore{
+		records: make(map[string]AuthorizerRecord),
+	}
+}
+
+//LoadAuthorizers 读取全部授权方
+func (s *MemoryAuthorizerStore) LoadAuthorizers() ([]AuthorizerRecord, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	records := make([]AuthorizerRecord, 0, len(s.records))
+	for _, r := range s.records {
+		records = append(records, r)
+	}
+	return records, nil
+}
+
+//SaveAuthorizer 保存授权方
+func (s *MemoryAuthorizerStore) SaveAuthorizer(record AuthorizerRecord) error {
+	s.lock.Lock()
+	s.records[record.Appid] = record
+	s.lock.Unlock()
+	return nil
+}
+
+//DeleteAuthorizer 删除授权方
+func (s *MemoryAuthorizerStore) DeleteAuthorizer(appid string) error {
+	s.lock.Lock()
+	delete(s.records, appid)
+	s.lock.Unlock()
+	return nil
+}
+
+//FileAuthorizerStore 文件授权方存储，以JSON格式保存在同一文件中
+type FileAuthorizerStore struct {
+	Path string
+	lock sync.Mutex
+}
+
+//NewFileAuthorizerStore 新建文件授权方存储
+func NewFileAuthorizerStore(path string) *FileAuthorizerStore {
+	return &FileAuthorizerStore{Path: path}
+}
+
+//LoadAuthorizers 读取全部授权方
+func (s *FileAuthorizerStore) LoadAuthorizers() ([]AuthorizerRecord, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	records, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	list := make([]AuthorizerRecord, 0, len(records))
+	for _, r := range records {
+		list = append(list, r)
+	}
+	return list, nil
+}
+
+//SaveAuthorizer 保存授权方
+func (s *FileAuthorizerStore) SaveAuthorizer(record AuthorizerRecord) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	records, err := s.load()
+	if err != nil {
+		return err
+	}
+	records[record.Appid] = record
+	return s.save(records)
+}
+
+//DeleteAuthorizer 删除授权方
+func (s *FileAuthorizerStore) DeleteAuthorizer(appid string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	records, err := s.load()
+	if err != nil {
+		return err
+	}
+	delete(records, appid)
+	return s.save(records)
+}
+
+func (s *FileAuthorizerStore) load() (map[string]AuthorizerRecord, error) {
+	records := make(map[string]AuthorizerRecord)
+	err := common.ReadJSONFile(s.Path, &records)
+	if err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+func (s *FileAuthorizerStore) save(records map[string]AuthorizerRecord) error {
+	return common.WriteJSONFile(s.Path, records)
+}
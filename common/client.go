@@ -0,0 +1,307 @@
+// Copyright 2020 wei_193 Author. All Rights Reserved.
+//
+// 微信接口客户端
+
+package common
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+//微信接口默认域名
+const (
+	APIHost    = "api.weixin.qq.com"
+	MchAPIHost = "api.mch.weixin.qq.com"
+)
+
+//RetryPolicy 系统繁忙等临时错误的重试策略
+type RetryPolicy struct {
+	//MaxRetries 最大重试次数，0表示不重试
+	MaxRetries int
+	//Backoff 首次重试前的等待时间，之后每次翻倍
+	Backoff time.Duration
+	//MaxBackoff 最长等待时间
+	MaxBackoff time.Duration
+}
+
+//DefaultRetryPolicy 默认重试策略
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries: 2,
+	Backoff:    200 * time.Millisecond,
+	MaxBackoff: 2 * time.Second,
+}
+
+//backoff 第n次重试前的等待时间
+func (p RetryPolicy) backoff(n int) time.Duration {
+	d := p.Backoff
+	for i := 0; i < n && d < p.MaxBackoff; i++ {
+		d *= 2
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	return d
+}
+
+//TokenRefresher 强制刷新令牌并返回新令牌，ctx为需要重放的请求的context
+type TokenRefresher func(ctx context.Context) (string, error)
+
+//Client 微信接口客户端
+type Client struct {
+	Retry RetryPolicy
+	//HTTPClient 发送请求使用的http.Client，为空时使用默认的60秒超时客户端
+	HTTPClient *http.Client
+	//BaseURL 替换api.weixin.qq.com的地址，如测试服务器或出口代理，为空时不替换
+	BaseURL string
+	//MchBaseURL 替换api.mch.weixin.qq.com的地址，为空时不替换
+	MchBaseURL string
+	//Logger 请求日志，为空时使用DefaultLogger
+	Logger Logger
+	//Observer 接口调用观察者，如*Metrics，为空时使用DefaultObserver
+	Observer Observer
+}
+
+//DefaultClient 默认客户端
+var DefaultClient = &Client{Retry: DefaultRetryPolicy}
+
+//defaultHTTPClient 未设置HTTPClient时使用的客户端
+var defaultHTTPClient = &http.Client{Timeout: 60 * time.Second}
+
+//NewClient 使用指定的http.Client和接口地址创建客户端，baseURL为空时使用微信默认地址
+func NewClient(hc *http.Client, baseURL string) *Client {
+	return &Client{
+		Retry:      DefaultRetryPolicy,
+		HTTPClient: hc,
+		BaseURL:    baseURL,
+		MchBaseURL: baseURL,
+	}
+}
+
+//httpClient 发送请求使用的http.Client
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return defaultHTTPClient
+}
+
+//Transport 客户端使用的Transport，未设置时返回http.DefaultTransport
+func (c *Client) Transport() http.RoundTripper {
+	if c.HTTPClient != nil && c.HTTPClient.Transport != nil {
+		return c.HTTPClient.Transport
+	}
+	return http.DefaultTransport
+}
+
+//WithHTTPClient 返回使用hc发送请求的客户端副本，重试策略和接口地址不变
+func (c *Client) WithHTTPClient(hc *http.Client) *Client {
+	n := *c
+	n.HTTPClient = hc
+	return &n
+}
+
+//ResolveURL 按BaseURL和MchBaseURL替换微信接口地址
+func (c *Client) ResolveURL(u *url.URL) (*url.URL, error) {
+	base := ""
+	switch u.Host {
+	case APIHost:
+		base = c.BaseURL
+	case MchAPIHost:
+		base = c.MchBaseURL
+	}
+	if base == "" {
+		return u, nil
+	}
+	b, err := url.Parse(base)
+	if err != nil {
+		return nil, err
+	}
+	n := *u
+	n.Scheme = b.Scheme
+	n.Host = b.Host
+	n.Path = strings.TrimSuffix(b.Path, "/") + u.Path
+	n.RawPath = ""
+	return &n, nil
+}
+
+//Do 替换接口地址后发送请求
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	u, err := c.ResolveURL(req.URL)
+	if err != nil {
+		return nil, err
+	}
+	if u != req.URL {
+		req = req.Clone(req.Context())
+		req.URL = u
+		req.Host = ""
+	}
+	return c.httpClient().Do(req)
+}
+
+//Requset 发送请求并返回响应内容，HTTP状态码不为200时返回*APIError
+func (c *Client) Requset(req *http.Request) ([]byte, error) {
+	start := time.Now()
+	status, resBody, err := c.requset(req)
+	c.observe(req, start, status, err)
+	return resBody, err
+}
+
+//requset 发送请求，返回http状态码及响应内容
+func (c *Client) requset(req *http.Request) (int, []byte, error) {
+	resp, err := c.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return resp.StatusCode, nil, &APIError{Endpoint: Endpoint(req), HTTPStatus: resp.StatusCode}
+	}
+	resBody, err := ioutil.ReadAll(resp.Body)
+	return resp.StatusCode, resBody, err
+}
+
+//RequsetXML 发送微信支付请求，失败时返回*APIError
+//isXML为false时不解析返回内容，如下载对账单
+func (c *Client) RequsetXML(req *http.Request, isXML ...bool) ([]byte, error) {
+	start := time.Now()
+	status, resBody, err := c.requset(req)
+	if err != nil {
+		c.record(req, start, status, nil, err)
+		return nil, err
+	}
+	if len(isXML) == 1 && !isXML[0] {
+		c.record(req, start, status, nil, nil)
+		return resBody, nil
+	}
+	err = CheckXMLError(req, resBody)
+	c.record(req, start, status, resBody, err)
+	if err != nil {
+		return resBody, err
+	}
+	return resBody, nil
+}
+
+//record 记录请求日志并通知Observer
+func (c *Client) record(req *http.Request, start time.Time, status int, resBody []byte, err error) {
+	c.logRequest(req, start, resBody, err)
+	c.observe(req, start, status, err)
+}
+
+//logRequest 记录请求结果，成功为Debug级别，可重试的错误为Warn级别，其他错误为Error级别
+func (c *Client) logRequest(req *http.Request, start time.Time, resBody []byte, err error) {
+	fields := append([]Field{
+		F("endpoint", Endpoint(req)),
+		F("latency", time.Since(start)),
+	}, LogFields(req.Context())...)
+	if e, ok := AsAPIError(err); ok {
+		if e.HTTPStatus != 0 {
+			fields = append(fields, F("http_status", e.HTTPStatus))
+		}
+		if e.Code != "" {
+			fields = append(fields, F("errcode", e.Code))
+		} else {
+			fields = append(fields, F("errcode", e.Errcode))
+		}
+		if e.Rid != "" {
+			fields = append(fields, F("rid", e.Rid))
+		}
+	}
+	switch {
+	case err == nil:
+		c.Log(LevelDebug, "wechat request", append(fields, F("response", resBody))...)
+	case IsTokenExpired(err) || IsSystemBusy(err) || IsRateLimited(err):
+		c.Log(LevelWarn, "wechat request failed", append(fields, F("error", err))...)
+	default:
+		c.Log(LevelError, "wechat request failed", append(fields, F("error", err))...)
+	}
+}
+
+//RequsetJSON 发送微信请求，errcode不为0时返回*APIError
+//请求URL中的tokenParam参数对应的令牌无效、过期或为空时，调用refresh刷新一次并使用新令牌重放请求；
+//系统繁忙等临时错误按重试策略退避重试
+func (c *Client) RequsetJSON(req *http.Request, tokenParam string, refresh TokenRefresher) ([]byte, error) {
+	refreshed := false
+	retries := 0
+	for {
+		start := time.Now()
+		status, resBody, err := c.requset(req)
+		if err == nil {
+			err = CheckJSONError(req, resBody)
+		}
+		c.record(req, start, status, resBody, err)
+		if err == nil {
+			return resBody, nil
+		}
+
+		switch {
+		case !refreshed && refresh != nil && tokenParam != "" && tokenRejected(req, tokenParam, err):
+			refreshed = true
+			token, rerr := refresh(req.Context())
+			if rerr != nil {
+				return resBody, err
+			}
+			next, rerr := ReplayRequest(req, tokenParam, token)
+			if rerr != nil {
+				return resBody, err
+			}
+			req = next
+		case IsSystemBusy(err) && retries < c.Retry.MaxRetries:
+			next, rerr := ReplayRequest(req, "", "")
+			if rerr != nil {
+				return resBody, err
+			}
+			select {
+			case <-req.Context().Done():
+				return resBody, err
+			case <-time.After(c.Retry.backoff(retries)):
+			}
+			retries++
+			req = next
+		default:
+			return resBody, err
+		}
+	}
+}
+
+//tokenRejected 请求URL中带有tokenParam参数且令牌无效、过期，或令牌为空导致缺少参数
+func tokenRejected(req *http.Request, tokenParam string, err error) bool {
+	v, ok := req.URL.Query()[tokenParam]
+	if !ok {
+		return false
+	}
+	if IsTokenExpired(err) {
+		return true
+	}
+	e, isAPI := AsAPIError(err)
+	return isAPI && e.Errcode == ErrcodeAccessTokenMissing && (len(v) == 0 || v[0] == "")
+}
+
+//errNoGetBody 请求体无法重新读取
+var errNoGetBody = errors.New("request body cannot be replayed")
+
+//ReplayRequest 复制请求以便重新发送，tokenParam不为空时替换URL中的令牌参数
+func ReplayRequest(req *http.Request, tokenParam, token string) (*http.Request, error) {
+	next := req.Clone(req.Context())
+	if req.Body != nil && req.Body != http.NoBody {
+		if req.GetBody == nil {
+			return nil, errNoGetBody
+		}
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		next.Body = body
+	}
+	if tokenParam != "" {
+		q := next.URL.Query()
+		q.Set(tokenParam, token)
+		next.URL.RawQuery = q.Encode()
+	}
+	return next, nil
+}
@@ -0,0 +1,115 @@
+package common
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestClientRefreshToken(t *testing.T) {
+	var bodies []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		bodies = append(bodies, string(b))
+		if r.URL.Query().Get("access_token") == "" {
+			w.Write([]byte(`{"errcode":41001,"errmsg":"access_token missing"}`))
+			return
+		}
+		if r.URL.Query().Get("access_token") != "new" {
+			w.Write([]byte(`{"errcode":40001,"errmsg":"invalid credential"}`))
+			return
+		}
+		w.Write([]byte(`{"errcode":0,"errmsg":"ok"}`))
+	}))
+	defer ts.Close()
+
+	refreshed := 0
+	refresh := func(ctx context.Context) (string, error) {
+		refreshed++
+		return "new", nil
+	}
+	req, _ := http.NewRequest("POST", ts.URL+"/cgi-bin/menu/create?access_token=old", bytes.NewReader([]byte("body")))
+	_, err := DefaultClient.RequsetJSON(req, "access_token", refresh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refreshed != 1 || len(bodies) != 2 || bodies[1] != "body" {
+		t.Errorf("refreshed = %d, bodies = %q", refreshed, bodies)
+	}
+
+	refresh = func(ctx context.Context) (string, error) { return "still-old", nil }
+	req, _ = http.NewRequest("GET", ts.URL+"/cgi-bin/menu/get?access_token=old", nil)
+	_, err = DefaultClient.RequsetJSON(req, "access_token", refresh)
+	if !IsTokenExpired(err) {
+		t.Errorf("err = %v, want token expired after a single refresh", err)
+	}
+
+	//令牌为空时同样刷新
+	refresh = func(ctx context.Context) (string, error) { return "new", nil }
+	req, _ = http.NewRequest("GET", ts.URL+"/cgi-bin/menu/get?access_token=", nil)
+	if _, err = DefaultClient.RequsetJSON(req, "access_token", refresh); err != nil {
+		t.Errorf("err = %v, want refreshed after empty token", err)
+	}
+}
+
+func TestClientRetryBusy(t *testing.T) {
+	calls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		switch calls {
+		case 1:
+			w.WriteHeader(http.StatusBadGateway)
+		case 2:
+			w.Write([]byte(`{"errcode":-1,"errmsg":"system error"}`))
+		default:
+			w.Write([]byte(`{"errcode":0,"errmsg":"ok"}`))
+		}
+	}))
+	defer ts.Close()
+
+	c := &Client{Retry: RetryPolicy{MaxRetries: 2, Backoff: time.Millisecond}}
+	req, _ := http.NewRequest("GET", ts.URL+"/cgi-bin/getcallbackip", nil)
+	if _, err := c.RequsetJSON(req, "", nil); err != nil || calls != 3 {
+		t.Errorf("err = %v, calls = %d", err, calls)
+	}
+
+	calls = 0
+	c.Retry.MaxRetries = 1
+	req, _ = http.NewRequest("GET", ts.URL+"/cgi-bin/getcallbackip", nil)
+	if _, err := c.RequsetJSON(req, "", nil); !IsSystemBusy(err) || calls != 2 {
+		t.Errorf("err = %v, calls = %d", err, calls)
+	}
+}
+
+func TestClientBaseURL(t *testing.T) {
+	var paths []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path+"?"+r.URL.RawQuery)
+		w.Write([]byte(`{"errcode":0}`))
+	}))
+	defer ts.Close()
+
+	c := NewClient(ts.Client(), ts.URL+"/wx/")
+	for _, u := range []string{
+		"https://api.weixin.qq.com/cgi-bin/token?appid=a",
+		"https://api.mch.weixin.qq.com/pay/orderquery",
+	} {
+		req, _ := http.NewRequest("GET", u, nil)
+		if _, err := c.RequsetJSON(req, "", nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := []string{"/wx/cgi-bin/token?appid=a", "/wx/pay/orderquery?"}
+	if len(paths) != 2 || paths[0] != want[0] || paths[1] != want[1] {
+		t.Errorf("paths = %q, want %q", paths, want)
+	}
+
+	u, _ := http.NewRequest("GET", "https://example.com/a", nil)
+	if got, _ := c.ResolveURL(u.URL); got.String() != "https://example.com/a" {
+		t.Errorf("ResolveURL(other host) = %s", got)
+	}
+}
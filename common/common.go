@@ -5,9 +5,7 @@ import (
 	"crypto/sha1"
 	"crypto/tls"
 	"crypto/x509"
-	"encoding/json"
 	"encoding/xml"
-	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -237,52 +235,17 @@ func GetTLSConfig(certpath, keypath, capath string) (*tls.Config, error) {
 	}, nil
 }
 
-//RequsetJSON 发送微信请求
+//RequsetJSON 使用DefaultClient发送微信请求，errcode不为0时返回*APIError
 func RequsetJSON(req *http.Request, tflag int) ([]byte, error) {
-
-	resBody, err := Requset(req)
-	if err != nil {
-		return nil, err
-	}
-	var errcode JSONError
-	err = json.Unmarshal(resBody, &errcode)
-	if err == nil && errcode.Errcode != 0 {
-		return resBody, errors.New(string(resBody))
-	}
-	return resBody, err
+	return DefaultClient.RequsetJSON(req, "", nil)
 }
 
-//RequsetXML 发送微信请求
+//RequsetXML 使用DefaultClient发送微信支付请求，失败时返回*APIError
 func RequsetXML(req *http.Request, tflag int, isXML ...bool) ([]byte, error) {
-	resBody, err := Requset(req)
-	if err != nil {
-		return nil, err
-	}
-	if len(isXML) == 1 && !isXML[0] {
-		return resBody, nil
-	}
-	var errcode XMLError
-	err = xml.Unmarshal(resBody, &errcode)
-	if err != nil ||
-		errcode.ReturnCode != "SUCCESS" ||
-		errcode.ResultCode != "SUCCESS" ||
-		errcode.ErrCode != "" {
-		return resBody, errors.New(string(resBody))
-	}
-
-	return resBody, nil
+	return DefaultClient.RequsetXML(req, isXML...)
 }
 
-//Requset Requset
+//Requset 使用DefaultClient发送请求
 func Requset(req *http.Request) ([]byte, error) {
-	client := &http.Client{Timeout: 60 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	if resp.StatusCode != http.StatusOK {
-		return nil, errors.New(resp.Status + http.StatusText(resp.StatusCode))
-	}
-	defer resp.Body.Close()
-	return ioutil.ReadAll(resp.Body)
+	return DefaultClient.Requset(req)
 }
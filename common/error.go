@@ -0,0 +1,179 @@
+// Copyright 2020 wei_193 Author. All Rights Reserved.
+//
+// 微信接口错误
+
+package common
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+//APIError 微信接口返回的错误
+type APIError struct {
+	//Endpoint 请求的接口，不含参数
+	Endpoint string
+	//HTTPStatus 非200的http状态码
+	HTTPStatus int
+	Errcode    int
+	Errmsg     string
+	//Rid 微信返回的请求id，errmsg中的rid部分
+	Rid string
+	//Code 支付接口的err_code，通信失败时为return_code
+	Code string
+}
+
+func (e *APIError) Error() string {
+	switch {
+	case e.Code != "":
+		return fmt.Sprintf("wechat %s: %s %s", e.Endpoint, e.Code, e.Errmsg)
+	case e.HTTPStatus != 0:
+		return fmt.Sprintf("wechat %s: http status %d", e.Endpoint, e.HTTPStatus)
+	}
+	msg := fmt.Sprintf("wechat %s: errcode %d %s", e.Endpoint, e.Errcode, e.Errmsg)
+	if e.Rid != "" {
+		msg += " rid: " + e.Rid
+	}
+	return msg
+}
+
+//错误码
+const (
+	ErrcodeSystemBusy          = -1
+	ErrcodeInvalidCredential   = 40001
+	ErrcodeInvalidAccessToken  = 40014
+	ErrcodeAccessTokenMissing  = 41001
+	ErrcodeAccessTokenExpired  = 42001
+	ErrcodeAPIFreqOutOfLimit   = 45009
+	ErrcodeAPIMinuteQuotaLimit = 45011
+	ErrcodeDataFormatError     = 47001
+)
+
+//IsTokenExpired 是否为access_token无效或过期
+func (e *APIError) IsTokenExpired() bool {
+	switch e.Errcode {
+	case ErrcodeInvalidCredential, ErrcodeInvalidAccessToken, ErrcodeAccessTokenExpired:
+		return true
+	}
+	return false
+}
+
+//IsRateLimited 是否为调用频率或次数超限
+func (e *APIError) IsRateLimited() bool {
+	switch e.Errcode {
+	case ErrcodeAPIFreqOutOfLimit, ErrcodeAPIMinuteQuotaLimit:
+		return true
+	}
+	return e.HTTPStatus == http.StatusTooManyRequests || e.Code == "FREQUENCY_LIMITED"
+}
+
+//IsInvalidArgument 是否为请求参数错误
+//包括400xx不合法的参数（令牌错误除外）、410xx缺少参数及47001数据格式错误
+func (e *APIError) IsInvalidArgument() bool {
+	switch {
+	case e.Errcode == ErrcodeDataFormatError:
+		return true
+	case e.Errcode >= 41000 && e.Errcode < 42000:
+		return true
+	case e.Errcode > 40001 && e.Errcode < 41000 && !e.IsTokenExpired():
+		return true
+	}
+	return e.Code == "PARAM_ERROR" || e.Code == "INVALID_REQUEST"
+}
+
+//IsSystemBusy 是否为系统繁忙等可重试的临时错误
+func (e *APIError) IsSystemBusy() bool {
+	return e.Errcode == ErrcodeSystemBusy || e.HTTPStatus >= 500 || e.Code == "SYSTEMERROR"
+}
+
+//AsAPIError 取出错误链中的APIError
+func AsAPIError(err error) (*APIError, bool) {
+	var e *APIError
+	if errors.As(err, &e) {
+		return e, true
+	}
+	return nil, false
+}
+
+//IsTokenExpired 是否为access_token无效或过期
+func IsTokenExpired(err error) bool {
+	e, ok := AsAPIError(err)
+	return ok && e.IsTokenExpired()
+}
+
+//IsRateLimited 是否为调用频率或次数超限
+func IsRateLimited(err error) bool {
+	e, ok := AsAPIError(err)
+	return ok && e.IsRateLimited()
+}
+
+//IsInvalidArgument 是否为请求参数错误
+func IsInvalidArgument(err error) bool {
+	e, ok := AsAPIError(err)
+	return ok && e.IsInvalidArgument()
+}
+
+//IsSystemBusy 是否为系统繁忙等可重试的临时错误
+func IsSystemBusy(err error) bool {
+	e, ok := AsAPIError(err)
+	return ok && e.IsSystemBusy()
+}
+
+//Endpoint 请求的接口地址，不含参数
+func Endpoint(req *http.Request) string {
+	if req == nil || req.URL == nil {
+		return ""
+	}
+	return req.URL.Host + req.URL.Path
+}
+
+//NewAPIError 生成接口错误，并从errmsg中取出rid
+func NewAPIError(req *http.Request, errcode int, errmsg string) *APIError {
+	e := &APIError{
+		Endpoint: Endpoint(req),
+		Errcode:  errcode,
+		Errmsg:   errmsg,
+	}
+	if i := strings.LastIndex(errmsg, "rid:"); i != -1 {
+		e.Rid = strings.TrimSpace(errmsg[i+len("rid:"):])
+		e.Errmsg = strings.TrimSpace(errmsg[:i])
+	}
+	return e
+}
+
+//CheckJSONError 检查JSON响应中的errcode，不为0时返回APIError
+func CheckJSONError(req *http.Request, body []byte) error {
+	var errcode JSONError
+	err := json.Unmarshal(body, &errcode)
+	if err != nil {
+		return err
+	}
+	if errcode.Errcode != 0 {
+		return NewAPIError(req, errcode.Errcode, errcode.Errmsg)
+	}
+	return nil
+}
+
+//CheckXMLError 检查支付接口XML响应，通信或业务失败时返回APIError
+func CheckXMLError(req *http.Request, body []byte) error {
+	var errcode XMLError
+	err := xml.Unmarshal(body, &errcode)
+	if err != nil {
+		return err
+	}
+	switch {
+	case errcode.ReturnCode != "SUCCESS":
+		return &APIError{Endpoint: Endpoint(req), Code: errcode.ReturnCode, Errmsg: errcode.ReturnMsg}
+	case errcode.ResultCode != "SUCCESS" || errcode.ErrCode != "":
+		code := errcode.ErrCode
+		if code == "" {
+			code = errcode.ResultCode
+		}
+		return &APIError{Endpoint: Endpoint(req), Code: code, Errmsg: errcode.ErrCodeDes}
+	}
+	return nil
+}
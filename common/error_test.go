@@ -0,0 +1,57 @@
+package common
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestCheckJSONError(t *testing.T) {
+	req, _ := http.NewRequest("GET", "https://api.weixin.qq.com/cgi-bin/user/info?access_token=secret", nil)
+	err := CheckJSONError(req, []byte(`{"errcode":42001,"errmsg":"access_token expired rid: 5f1a-2b3c"}`))
+	e, ok := AsAPIError(fmt.Errorf("wrapped: %w", err))
+	if !ok {
+		t.Fatalf("err = %v, want *APIError", err)
+	}
+	if e.Endpoint != "api.weixin.qq.com/cgi-bin/user/info" || e.Errcode != 42001 ||
+		e.Errmsg != "access_token expired" || e.Rid != "5f1a-2b3c" {
+		t.Errorf("APIError = %+v", e)
+	}
+	if !IsTokenExpired(err) || IsRateLimited(err) || IsInvalidArgument(err) {
+		t.Errorf("classification wrong for %v", err)
+	}
+	if err = CheckJSONError(req, []byte(`{"errcode":0,"errmsg":"ok"}`)); err != nil {
+		t.Errorf("errcode 0: %v", err)
+	}
+
+	cases := []struct {
+		errcode                   int
+		expired, limited, invalid bool
+	}{
+		{40001, true, false, false},
+		{40003, false, false, true},
+		{41001, false, false, true},
+		{45009, false, true, false},
+		{-1, false, false, false},
+	}
+	for _, c := range cases {
+		e := NewAPIError(req, c.errcode, "")
+		if e.IsTokenExpired() != c.expired || e.IsRateLimited() != c.limited || e.IsInvalidArgument() != c.invalid {
+			t.Errorf("errcode %d: expired=%v limited=%v invalid=%v", c.errcode,
+				e.IsTokenExpired(), e.IsRateLimited(), e.IsInvalidArgument())
+		}
+	}
+}
+
+func TestCheckXMLError(t *testing.T) {
+	req, _ := http.NewRequest("POST", "https://api.mch.weixin.qq.com/pay/orderquery", nil)
+	err := CheckXMLError(req, []byte(`<xml><return_code>SUCCESS</return_code><result_code>FAIL</result_code>`+
+		`<err_code>SYSTEMERROR</err_code><err_code_des>busy</err_code_des></xml>`))
+	if !IsSystemBusy(err) {
+		t.Errorf("err = %v, want system busy", err)
+	}
+	err = CheckXMLError(req, []byte(`<xml><return_code>SUCCESS</return_code><result_code>SUCCESS</result_code></xml>`))
+	if err != nil {
+		t.Errorf("success: %v", err)
+	}
+}
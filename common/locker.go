@@ -0,0 +1,101 @@
+// Copyright 2020 wei_193 Author. All Rights Reserved.
+//
+// 令牌刷新锁
+
+package common
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+//Locker 令牌刷新锁，多个实例共享令牌存储时用于保证同一令牌同时只有一个刷新请求
+type Locker interface {
+	Lock(key string) (unlock func(), err error)
+}
+
+//LockToken 获取指定令牌的刷新锁，未设置Locker时不加锁
+func LockToken(l Locker, appid string, kind TokenKind) (unlock func(), err error) {
+	if l == nil {
+		return func() {}, nil
+	}
+	return l.Lock(storeKey(appid, kind))
+}
+
+//MemoryLocker 进程内刷新锁
+type MemoryLocker struct {
+	lock  sync.Mutex
+	locks map[string]*sync.Mutex
+}
+
+//NewMemoryLocker 新建进程内刷新锁
+func NewMemoryLocker() *MemoryLocker {
+	return &MemoryLocker{
+		locks: make(map[string]*sync.Mutex),
+	}
+}
+
+//Lock 加锁
+func (l *MemoryLocker) Lock(key string) (unlock func(), err error) {
+	l.lock.Lock()
+	m, ok := l.locks[key]
+	if !ok {
+		m = new(sync.Mutex)
+		l.locks[key] = m
+	}
+	l.lock.Unlock()
+	m.Lock()
+	return m.Unlock, nil
+}
+
+//ErrLockTimeout 等待锁超时
+var ErrLockTimeout = errors.New("lock timeout")
+
+//FileLocker 文件刷新锁，在Dir目录下创建锁文件，适用于同一主机上的多个进程
+type FileLocker struct {
+	Dir string
+	//Timeout 等待锁的最长时间，需不小于持有锁的最长时间，
+	//否则持有者正常进行较慢的刷新时，等待者会超时失败
+	Timeout time.Duration
+	//Stale 锁文件超过该时间视为持有者已退出，需大于持有锁的最长时间，
+	//即刷新请求的超时（默认60秒）加上重试的时间
+	Stale time.Duration
+}
+
+//NewFileLocker 新建文件刷新锁，等待时间与锁文件过期时间相同，持有者退出未释放时等待者也能在超时前取得锁
+func NewFileLocker(dir string) *FileLocker {
+	return &FileLocker{
+		Dir:     dir,
+		Timeout: 3 * time.Minute,
+		Stale:   3 * time.Minute,
+	}
+}
+
+//Lock 加锁
+func (l *FileLocker) Lock(key string) (unlock func(), err error) {
+	path := filepath.Join(l.Dir, strings.Replace(key, ":", "_", -1)+".lock")
+	deadline := time.Now().Add(l.Timeout)
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(path) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+		if info, err := os.Stat(path); err == nil && l.Stale > 0 &&
+			time.Since(info.ModTime()) > l.Stale {
+			os.Remove(path)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, ErrLockTimeout
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
@@ -0,0 +1,98 @@
+package common
+
+import (
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+func testLocker(t *testing.T, l Locker) {
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		holders int
+	)
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			unlock, err := LockToken(l, "wx1", TokenAccessToken)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			mu.Lock()
+			holders++
+			if holders > 1 {
+				t.Error("lock held concurrently")
+			}
+			mu.Unlock()
+			time.Sleep(10 * time.Millisecond)
+			mu.Lock()
+			holders--
+			mu.Unlock()
+			unlock()
+		}()
+	}
+	wg.Wait()
+}
+
+func TestMemoryLocker(t *testing.T) {
+	testLocker(t, NewMemoryLocker())
+}
+
+func TestFileLocker(t *testing.T) {
+	dir, err := ioutil.TempDir("", "locker")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	testLocker(t, NewFileLocker(dir))
+
+	l := NewFileLocker(dir)
+	l.Timeout = 100 * time.Millisecond
+	unlock, err := l.Lock("wx1:access_token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unlock()
+	if _, err = l.Lock("wx1:access_token"); err != ErrLockTimeout {
+		t.Errorf("second Lock = %v, want ErrLockTimeout", err)
+	}
+}
+
+func TestFileLockerSlowHolder(t *testing.T) {
+	dir, err := ioutil.TempDir("", "locker")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if l := NewFileLocker(dir); l.Timeout < l.Stale {
+		t.Errorf("default Timeout %s shorter than Stale %s", l.Timeout, l.Stale)
+	}
+
+	//持有者的刷新耗时接近Stale时，等待者应等到锁被释放
+	l := &FileLocker{Dir: dir, Timeout: 500 * time.Millisecond, Stale: 500 * time.Millisecond}
+	unlock, err := l.Lock("wx1:access_token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	released := make(chan struct{})
+	go func() {
+		time.Sleep(300 * time.Millisecond)
+		close(released)
+		unlock()
+	}()
+	unlock2, err := l.Lock("wx1:access_token")
+	if err != nil {
+		t.Fatalf("waiter Lock = %v", err)
+	}
+	defer unlock2()
+	select {
+	case <-released:
+	default:
+		t.Error("waiter acquired the lock before the holder released it")
+	}
+}
@@ -0,0 +1,172 @@
+// Copyright 2020 wei_193 Author. All Rights Reserved.
+//
+// 日志
+
+package common
+
+import (
+	"context"
+	"regexp"
+	"strings"
+)
+
+//Level 日志级别，取值与log/slog一致
+type Level int
+
+//日志级别
+const (
+	LevelDebug Level = -4
+	LevelInfo  Level = 0
+	LevelWarn  Level = 4
+	LevelError Level = 8
+)
+
+//String 日志级别名称
+func (l Level) String() string {
+	switch {
+	case l < LevelInfo:
+		return "DEBUG"
+	case l < LevelWarn:
+		return "INFO"
+	case l < LevelError:
+		return "WARN"
+	}
+	return "ERROR"
+}
+
+//Field 日志字段
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+//F 创建日志字段
+func F(key string, value interface{}) Field {
+	return Field{Key: key, Value: value}
+}
+
+//Logger 分级日志接口
+//库内输出的字段已经过Redact处理，实现无需再次脱敏
+type Logger interface {
+	Log(level Level, msg string, fields ...Field)
+}
+
+//nopLogger 不输出任何日志
+type nopLogger struct{}
+
+func (nopLogger) Log(level Level, msg string, fields ...Field) {}
+
+//NopLogger 不输出任何日志的Logger
+var NopLogger Logger = nopLogger{}
+
+//DefaultLogger Client未设置Logger时使用的日志，默认不输出
+var DefaultLogger = NopLogger
+
+//secretKeys 需要脱敏的参数名
+var secretKeys = []string{
+	"access_token", "component_access_token", "authorizer_access_token",
+	"refresh_token", "authorizer_refresh_token", "component_verify_ticket",
+	"pre_auth_code", "auth_code", "authorization_code", "code", "js_code",
+	"ticket", "jsapi_ticket", "secret", "appsecret", "component_appsecret",
+	"key", "paykey", "pay_key", "sign", "paySign", "session_key",
+	"openid", "re_openid", "next_openid", "unionid",
+}
+
+var (
+	secretKeySet = func() map[string]bool {
+		m := make(map[string]bool)
+		for _, k := range secretKeys {
+			m[strings.ToLower(k)] = true
+		}
+		return m
+	}()
+	secretAlt   = strings.Join(secretKeys, "|")
+	redactJSON  = regexp.MustCompile(`"(` + secretAlt + `)"(\s*:\s*)("[^"]*"|\[(?:\s*"[^"]*"\s*,?)*\s*\])`)
+	redactQuery = regexp.MustCompile(`([?&](?:` + secretAlt + `)=)[^&\s"]*`)
+	redactXML   = regexp.MustCompile(`<(` + secretAlt + `)>(?:<!\[CDATA\[)?[^<\]]*(?:\]\]>)?</(` + secretAlt + `)>`)
+)
+
+//Mask 隐藏敏感值，仅保留前4个字符
+func Mask(s string) string {
+	if len(s) <= 8 {
+		return "***"
+	}
+	return s[:4] + "***"
+}
+
+//IsSecretKey 参数名是否为令牌、密钥、openid等敏感字段
+func IsSecretKey(key string) bool {
+	return secretKeySet[strings.ToLower(key)]
+}
+
+//Redact 隐藏文本中JSON、XML和URL参数形式的令牌、密钥和openid
+func Redact(s string) string {
+	s = redactJSON.ReplaceAllStringFunc(s, func(m string) string {
+		sub := redactJSON.FindStringSubmatch(m)
+		if strings.HasPrefix(sub[3], "[") {
+			return `"` + sub[1] + `"` + sub[2] + `["***"]`
+		}
+		return `"` + sub[1] + `"` + sub[2] + `"***"`
+	})
+	s = redactQuery.ReplaceAllString(s, `${1}***`)
+	s = redactXML.ReplaceAllStringFunc(s, func(m string) string {
+		sub := redactXML.FindStringSubmatch(m)
+		if sub[1] != sub[2] {
+			return m
+		}
+		return "<" + sub[1] + ">***</" + sub[1] + ">"
+	})
+	return s
+}
+
+//RedactField 脱敏日志字段，敏感字段只保留前4个字符，文本和错误按Redact处理
+func RedactField(f Field) Field {
+	if IsSecretKey(f.Key) {
+		if s, ok := f.Value.(string); ok {
+			return Field{Key: f.Key, Value: Mask(s)}
+		}
+		return Field{Key: f.Key, Value: "***"}
+	}
+	switch v := f.Value.(type) {
+	case string:
+		return Field{Key: f.Key, Value: Redact(v)}
+	case []byte:
+		return Field{Key: f.Key, Value: Redact(string(v))}
+	case error:
+		return Field{Key: f.Key, Value: Redact(v.Error())}
+	}
+	return f
+}
+
+//Log 脱敏后输出日志，未设置Logger时使用DefaultLogger
+func (c *Client) Log(level Level, msg string, fields ...Field) {
+	l := c.Logger
+	if l == nil {
+		l = DefaultLogger
+	}
+	if l == NopLogger {
+		return
+	}
+	redacted := make([]Field, len(fields))
+	for i, f := range fields {
+		redacted[i] = RedactField(f)
+	}
+	l.Log(level, msg, redacted...)
+}
+
+type logFieldsKey struct{}
+
+//WithLogFields 返回携带日志字段的context，Client记录请求日志时附加这些字段
+func WithLogFields(ctx context.Context, fields ...Field) context.Context {
+	prev := LogFields(ctx)
+	all := make([]Field, 0, len(prev)+len(fields))
+	all = append(all, prev...)
+	all = append(all, fields...)
+	return context.WithValue(ctx, logFieldsKey{}, all)
+}
+
+//LogFields context中携带的日志字段
+func LogFields(ctx context.Context) []Field {
+	fields, _ := ctx.Value(logFieldsKey{}).([]Field)
+	return fields
+}
@@ -0,0 +1,35 @@
+//go:build go1.21
+// +build go1.21
+
+package common
+
+import (
+	"context"
+	"log/slog"
+)
+
+//SlogLogger 使用log/slog输出日志
+type SlogLogger struct {
+	Logger *slog.Logger
+}
+
+//NewSlogLogger 创建输出到l的Logger，l为空时使用slog.Default()
+func NewSlogLogger(l *slog.Logger) *SlogLogger {
+	if l == nil {
+		l = slog.Default()
+	}
+	return &SlogLogger{Logger: l}
+}
+
+//Log 输出日志
+func (l *SlogLogger) Log(level Level, msg string, fields ...Field) {
+	ctx := context.Background()
+	if !l.Logger.Enabled(ctx, slog.Level(level)) {
+		return
+	}
+	attrs := make([]slog.Attr, len(fields))
+	for i, f := range fields {
+		attrs[i] = slog.Any(f.Key, f.Value)
+	}
+	l.Logger.LogAttrs(ctx, slog.Level(level), msg, attrs...)
+}
@@ -0,0 +1,66 @@
+package common
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRedact(t *testing.T) {
+	for _, c := range []struct{ in, want string }{
+		{`{"access_token":"ACCESS_TOKEN_VALUE","expires_in":7200}`, `{"access_token":"***","expires_in":7200}`},
+		{`{"authorizer_refresh_token": "abc","errcode":0}`, `{"authorizer_refresh_token": "***","errcode":0}`},
+		{`https://api.weixin.qq.com/cgi-bin/user/info?access_token=TOKEN&openid=o6_bmjrPTlm6`, `https://api.weixin.qq.com/cgi-bin/user/info?access_token=***&openid=***`},
+		{`<xml><sign><![CDATA[C380BEC2BF]]></sign><openid>oUpF8uMuAJO</openid><total_fee>1</total_fee></xml>`, `<xml><sign>***</sign><openid>***</openid><total_fee>1</total_fee></xml>`},
+		{`{"data":{"openid":["OPENID1", "OPENID2"]},"next_openid":"OPENID2"}`, `{"data":{"openid":["***"]},"next_openid":"***"}`},
+		{`{"openid":[]}`, `{"openid":["***"]}`},
+		{`{"errcode":40001,"errmsg":"invalid credential"}`, `{"errcode":40001,"errmsg":"invalid credential"}`},
+	} {
+		if got := Redact(c.in); got != c.want {
+			t.Errorf("Redact(%s) = %s, want %s", c.in, got, c.want)
+		}
+	}
+}
+
+type recordLogger struct {
+	msgs   []string
+	fields [][]Field
+}
+
+func (l *recordLogger) Log(level Level, msg string, fields ...Field) {
+	l.msgs = append(l.msgs, level.String()+" "+msg)
+	l.fields = append(l.fields, fields)
+}
+
+func TestClientLog(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"errcode":40013,"errmsg":"invalid appid"}`))
+	}))
+	defer ts.Close()
+
+	l := &recordLogger{}
+	c := NewClient(nil, "")
+	c.Logger = l
+	req, _ := http.NewRequest("GET", ts.URL+"/cgi-bin/token?secret=APPSECRET", nil)
+	req = req.WithContext(WithLogFields(req.Context(), F("appid", "wx123")))
+	c.RequsetJSON(req, "", nil)
+	c.Log(LevelInfo, "test", F("paykey", "0123456789abcdef"), F("error", errors.New("Get ?access_token=SECRET: EOF")))
+
+	if len(l.msgs) != 2 || l.msgs[0] != "ERROR wechat request failed" {
+		t.Fatalf("msgs = %q", l.msgs)
+	}
+	got := map[string]interface{}{}
+	for _, f := range l.fields[0] {
+		got[f.Key] = f.Value
+	}
+	if got["appid"] != "wx123" || got["errcode"] != 40013 || got["latency"] == nil {
+		t.Errorf("fields = %v", got)
+	}
+	for _, f := range l.fields[1] {
+		if s, _ := f.Value.(string); strings.Contains(s, "SECRET") || strings.Contains(s, "abcdef") {
+			t.Errorf("field %s not redacted: %s", f.Key, s)
+		}
+	}
+}
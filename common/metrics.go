@@ -0,0 +1,170 @@
+// Copyright 2020 wei_193 Author. All Rights Reserved.
+//
+// Prometheus格式的接口调用统计
+
+package common
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+//DefaultLatencyBuckets 默认耗时分桶上限，单位秒
+var DefaultLatencyBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+//Metrics 统计接口调用次数及耗时的Observer，作为http.Handler输出Prometheus文本格式
+//
+//	m := common.NewMetrics()
+//	client.Observer = m
+//	http.Handle("/metrics", m)
+type Metrics struct {
+	//Namespace 指标名前缀，为空时为wechat
+	Namespace string
+	//Buckets 耗时分桶上限，单位秒，需升序，为空时使用DefaultLatencyBuckets
+	Buckets []float64
+
+	lock     sync.Mutex
+	requests map[callKey]uint64
+	latency  map[latencyKey]*histogram
+}
+
+type callKey struct {
+	endpoint, appid, status, errcode string
+}
+
+type latencyKey struct {
+	endpoint, appid string
+}
+
+type histogram struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+//NewMetrics 创建接口调用统计
+func NewMetrics() *Metrics {
+	return &Metrics{}
+}
+
+//ObserveCall 记录一次接口调用
+func (m *Metrics) ObserveCall(call Call) {
+	buckets := m.buckets()
+	seconds := call.Latency.Seconds()
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.requests == nil {
+		m.requests = make(map[callKey]uint64)
+		m.latency = make(map[latencyKey]*histogram)
+	}
+	m.requests[callKey{call.Endpoint, call.Appid, strconv.Itoa(call.HTTPStatus), call.Errcode}]++
+
+	k := latencyKey{call.Endpoint, call.Appid}
+	h := m.latency[k]
+	if h == nil {
+		h = &histogram{counts: make([]uint64, len(buckets))}
+		m.latency[k] = h
+	}
+	for i, le := range buckets {
+		if seconds <= le {
+			h.counts[i]++
+		}
+	}
+	h.sum += seconds
+	h.count++
+}
+
+//ServeHTTP 输出Prometheus文本格式的统计
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.WriteText(w)
+}
+
+//WriteText 以Prometheus文本格式写入统计
+func (m *Metrics) WriteText(out io.Writer) error {
+	w := bufio.NewWriter(out)
+	ns := m.Namespace
+	if ns == "" {
+		ns = "wechat"
+	}
+	buckets := m.buckets()
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	name := ns + "_api_requests_total"
+	fmt.Fprintf(w, "# HELP %s WeChat API requests by endpoint, appid, http status and errcode.\n", name)
+	fmt.Fprintf(w, "# TYPE %s counter\n", name)
+	keys := make([]callKey, 0, len(m.requests))
+	for k := range m.requests {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if a.endpoint != b.endpoint {
+			return a.endpoint < b.endpoint
+		}
+		if a.appid != b.appid {
+			return a.appid < b.appid
+		}
+		if a.status != b.status {
+			return a.status < b.status
+		}
+		return a.errcode < b.errcode
+	})
+	for _, k := range keys {
+		fmt.Fprintf(w, "%s{endpoint=%s,appid=%s,http_status=%s,errcode=%s} %d\n", name,
+			quoteLabel(k.endpoint), quoteLabel(k.appid), quoteLabel(k.status), quoteLabel(k.errcode), m.requests[k])
+	}
+
+	name = ns + "_api_request_duration_seconds"
+	fmt.Fprintf(w, "# HELP %s WeChat API request latency by endpoint and appid.\n", name)
+	fmt.Fprintf(w, "# TYPE %s histogram\n", name)
+	lkeys := make([]latencyKey, 0, len(m.latency))
+	for k := range m.latency {
+		lkeys = append(lkeys, k)
+	}
+	sort.Slice(lkeys, func(i, j int) bool {
+		a, b := lkeys[i], lkeys[j]
+		if a.endpoint != b.endpoint {
+			return a.endpoint < b.endpoint
+		}
+		return a.appid < b.appid
+	})
+	for _, k := range lkeys {
+		h := m.latency[k]
+		labels := "endpoint=" + quoteLabel(k.endpoint) + ",appid=" + quoteLabel(k.appid)
+		for i, le := range buckets {
+			if i >= len(h.counts) {
+				break
+			}
+			fmt.Fprintf(w, "%s_bucket{%s,le=\"%s\"} %d\n", name, labels,
+				strconv.FormatFloat(le, 'g', -1, 64), h.counts[i])
+		}
+		fmt.Fprintf(w, "%s_bucket{%s,le=\"+Inf\"} %d\n", name, labels, h.count)
+		fmt.Fprintf(w, "%s_sum{%s} %s\n", name, labels, strconv.FormatFloat(h.sum, 'g', -1, 64))
+		fmt.Fprintf(w, "%s_count{%s} %d\n", name, labels, h.count)
+	}
+	return w.Flush()
+}
+
+//buckets 耗时分桶上限
+func (m *Metrics) buckets() []float64 {
+	if len(m.Buckets) != 0 {
+		return m.Buckets
+	}
+	return DefaultLatencyBuckets
+}
+
+//labelEscaper 转义标签值
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+//quoteLabel 生成带引号的标签值
+func quoteLabel(v string) string {
+	return `"` + labelEscaper.Replace(v) + `"`
+}
@@ -0,0 +1,53 @@
+package common
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/cgi-bin/menu/get":
+			w.Write([]byte(`{"errcode":0,"errmsg":"ok"}`))
+		case "/cgi-bin/menu/create":
+			w.Write([]byte(`{"errcode":40013,"errmsg":"invalid appid"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	m := NewMetrics()
+	m.Buckets = []float64{60}
+	c := NewClient(nil, ts.URL)
+	c.Retry = RetryPolicy{}
+	c.Observer = m
+	ctx := WithLogFields(context.Background(), F("component_appid", "wxcomponent"), F("appid", "wxapp"))
+
+	for _, path := range []string{"/cgi-bin/menu/get", "/cgi-bin/menu/get", "/cgi-bin/menu/create"} {
+		req, _ := http.NewRequestWithContext(ctx, "GET", "https://api.weixin.qq.com"+path, nil)
+		c.RequsetJSON(req, "", nil)
+	}
+	req, _ := http.NewRequest("GET", "https://api.weixin.qq.com/notfound", nil)
+	c.Requset(req)
+
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	out := rec.Body.String()
+	for _, want := range []string{
+		`wechat_api_requests_total{endpoint="api.weixin.qq.com/cgi-bin/menu/get",appid="wxapp",http_status="200",errcode=""} 2`,
+		`wechat_api_requests_total{endpoint="api.weixin.qq.com/cgi-bin/menu/create",appid="wxapp",http_status="200",errcode="40013"} 1`,
+		`wechat_api_requests_total{endpoint="api.weixin.qq.com/notfound",appid="",http_status="404",errcode=""} 1`,
+		`wechat_api_request_duration_seconds_bucket{endpoint="api.weixin.qq.com/cgi-bin/menu/get",appid="wxapp",le="60"} 2`,
+		`wechat_api_request_duration_seconds_bucket{endpoint="api.weixin.qq.com/cgi-bin/menu/get",appid="wxapp",le="+Inf"} 2`,
+		`wechat_api_request_duration_seconds_count{endpoint="api.weixin.qq.com/cgi-bin/menu/get",appid="wxapp"} 2`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("metrics missing %s\n%s", want, out)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright 2020 wei_193 Author. All Rights Reserved.
+//
+// 接口调用观察
+
+package common
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+//Call 一次微信接口调用的结果
+type Call struct {
+	//Endpoint 接口地址，如api.weixin.qq.com/cgi-bin/token
+	Endpoint string
+	//Appid 发起调用的appid，第三方平台接口为component_appid，未知时为空
+	Appid string
+	//HTTPStatus http状态码，未收到响应时为0
+	HTTPStatus int
+	//Errcode 微信错误码，支付接口为err_code，成功或未收到响应时为空
+	Errcode string
+	Latency time.Duration
+	Err     error
+}
+
+//Observer 接口调用观察者，每次请求完成后调用，需支持并发调用
+//令牌过期重放和系统繁忙重试的每次请求分别调用
+type Observer interface {
+	ObserveCall(call Call)
+}
+
+//ObserverFunc 函数形式的Observer
+type ObserverFunc func(call Call)
+
+//ObserveCall 调用f
+func (f ObserverFunc) ObserveCall(call Call) {
+	f(call)
+}
+
+//DefaultObserver Client未设置Observer时使用的观察者，为空时不观察
+var DefaultObserver Observer
+
+//observe 通知Observer请求结果
+func (c *Client) observe(req *http.Request, start time.Time, status int, err error) {
+	o := c.Observer
+	if o == nil {
+		o = DefaultObserver
+	}
+	if o == nil {
+		return
+	}
+	call := Call{
+		Endpoint:   Endpoint(req),
+		Appid:      callAppid(req),
+		HTTPStatus: status,
+		Latency:    time.Since(start),
+		Err:        err,
+	}
+	if e, ok := AsAPIError(err); ok {
+		switch {
+		case e.Code != "":
+			call.Errcode = e.Code
+		case e.HTTPStatus == 0:
+			call.Errcode = strconv.Itoa(e.Errcode)
+		}
+	}
+	o.ObserveCall(call)
+}
+
+//callAppid 从请求的日志字段中取出appid，没有时使用component_appid
+func callAppid(req *http.Request) string {
+	appid := ""
+	for _, f := range LogFields(req.Context()) {
+		v, ok := f.Value.(string)
+		if !ok {
+			continue
+		}
+		switch f.Key {
+		case "appid":
+			return v
+		case "component_appid":
+			appid = v
+		}
+	}
+	return appid
+}
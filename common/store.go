@@ -0,0 +1,197 @@
+// Copyright 2020 wei_193 Author. All Rights Reserved.
+//
+// 令牌存储
+
+package common
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+//TokenKind 令牌类型
+type TokenKind string
+
+//令牌类型
+const (
+	TokenComponentVerifyTicket TokenKind = "component_verify_ticket"
+	TokenComponentAccessToken  TokenKind = "component_access_token"
+	TokenAuthorizerRefresh     TokenKind = "authorizer_refresh_token"
+	TokenAccessToken           TokenKind = "access_token"
+	TokenJsapiTicket           TokenKind = "jsapi_ticket"
+)
+
+//ErrTokenNotFound 令牌不存在或已过期
+var ErrTokenNotFound = errors.New("token not found")
+
+//TokenStore 令牌存储接口，按appid和令牌类型存取
+//expires为过期时间的unix时间戳，0表示不过期
+type TokenStore interface {
+	GetToken(appid string, kind TokenKind) (token string, expires int64, err error)
+	SetToken(appid string, kind TokenKind, token string, expires int64) error
+}
+
+//StoreToken 存储的令牌
+type StoreToken struct {
+	Token   string `json:"token"`
+	Expires int64  `json:"expires"`
+}
+
+//expired 是否已过期
+func (t StoreToken) expired() bool {
+	return t.Expires != 0 && t.Expires <= time.Now().Unix()
+}
+
+func storeKey(appid string, kind TokenKind) string {
+	return appid + ":" + string(kind)
+}
+
+//MemoryStore 内存令牌存储
+type MemoryStore struct {
+	lock   sync.RWMutex
+	tokens map[string]StoreToken
+}
+
+//NewMemoryStore 新建内存令牌存储
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		tokens: make(map[string]StoreToken),
+	}
+}
+
+//GetToken 获取令牌
+func (s *MemoryStore) GetToken(appid string, kind TokenKind) (token string, expires int64, err error) {
+	s.lock.RLock()
+	t, ok := s.tokens[storeKey(appid, kind)]
+	s.lock.RUnlock()
+	if !ok || t.expired() {
+		return "", 0, ErrTokenNotFound
+	}
+	return t.Token, t.Expires, nil
+}
+
+//SetToken 保存令牌
+func (s *MemoryStore) SetToken(appid string, kind TokenKind, token string, expires int64) error {
+	s.lock.Lock()
+	s.tokens[storeKey(appid, kind)] = StoreToken{Token: token, Expires: expires}
+	s.lock.Unlock()
+	return nil
+}
+
+//FileStore 文件令牌存储，所有令牌以JSON格式保存在同一文件中
+//每次读取都会重新加载文件，写入时通过Locker加锁，多个进程可共享同一文件
+type FileStore struct {
+	Path string
+	//Locker 写入文件的跨进程锁，为nil时只在进程内加锁
+	Locker Locker
+	lock   sync.Mutex
+}
+
+//NewFileStore 新建文件令牌存储，使用与文件同目录的锁文件保护写入
+func NewFileStore(path string) *FileStore {
+	return &FileStore{
+		Path: path,
+		Locker: &FileLocker{
+			Dir:     filepath.Dir(path),
+			Timeout: 10 * time.Second,
+			Stale:   10 * time.Second,
+		},
+	}
+}
+
+//GetToken 获取令牌
+func (s *FileStore) GetToken(appid string, kind TokenKind) (token string, expires int64, err error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	tokens, err := s.load()
+	if err != nil {
+		return "", 0, err
+	}
+	t, ok := tokens[storeKey(appid, kind)]
+	if !ok || t.expired() {
+		return "", 0, ErrTokenNotFound
+	}
+	return t.Token, t.Expires, nil
+}
+
+//SetToken 保存令牌
+func (s *FileStore) SetToken(appid string, kind TokenKind, token string, expires int64) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	//读取、修改、写入期间持有跨进程锁，避免覆盖其他进程写入的令牌
+	if s.Locker != nil {
+		unlock, err := s.Locker.Lock(filepath.Base(s.Path))
+		if err != nil {
+			return err
+		}
+		defer unlock()
+	}
+	tokens, err := s.load()
+	if err != nil {
+		return err
+	}
+	tokens[storeKey(appid, kind)] = StoreToken{Token: token, Expires: expires}
+	return s.save(tokens)
+}
+
+func (s *FileStore) load() (map[string]StoreToken, error) {
+	tokens := make(map[string]StoreToken)
+	err := ReadJSONFile(s.Path, &tokens)
+	if err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+func (s *FileStore) save(tokens map[string]StoreToken) error {
+	return WriteJSONFile(s.Path, tokens)
+}
+
+//ReadJSONFile 读取JSON文件到v，文件不存在或为空时不修改v
+func ReadJSONFile(path string, v interface{}) error {
+	buf, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if len(buf) == 0 {
+		return nil
+	}
+	return json.Unmarshal(buf, v)
+}
+
+//WriteJSONFile 将v写入JSON文件，先写入临时文件再重命名，避免其他进程读到不完整的内容
+func WriteJSONFile(path string, v interface{}) error {
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(buf)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+//TokenAhead 令牌提前刷新的秒数
+const TokenAhead = 300
+
+//TokenValid 判断令牌是否在有效期内，距离过期不足TokenAhead秒视为无效
+func TokenValid(token string, expires int64) bool {
+	return token != "" && expires-TokenAhead > time.Now().Unix()
+}
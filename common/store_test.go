@@ -0,0 +1,89 @@
+package common
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func testTokenStore(t *testing.T, s TokenStore) {
+	_, _, err := s.GetToken("wx1", TokenAccessToken)
+	if err != ErrTokenNotFound {
+		t.Fatalf("GetToken on empty store: %v", err)
+	}
+
+	expires := time.Now().Unix() + 7200
+	if err = s.SetToken("wx1", TokenAccessToken, "token1", expires); err != nil {
+		t.Fatal(err)
+	}
+	if err = s.SetToken("wx1", TokenAuthorizerRefresh, "refresh1", 0); err != nil {
+		t.Fatal(err)
+	}
+	if err = s.SetToken("wx2", TokenAccessToken, "expired", time.Now().Unix()-1); err != nil {
+		t.Fatal(err)
+	}
+
+	token, exp, err := s.GetToken("wx1", TokenAccessToken)
+	if err != nil || token != "token1" || exp != expires {
+		t.Errorf("GetToken = %q, %d, %v", token, exp, err)
+	}
+	token, _, err = s.GetToken("wx1", TokenAuthorizerRefresh)
+	if err != nil || token != "refresh1" {
+		t.Errorf("GetToken refresh = %q, %v", token, err)
+	}
+	_, _, err = s.GetToken("wx2", TokenAccessToken)
+	if err != ErrTokenNotFound {
+		t.Errorf("GetToken expired: %v", err)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	testTokenStore(t, NewMemoryStore())
+}
+
+func TestFileStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "tokens.json")
+	testTokenStore(t, NewFileStore(path))
+
+	token, _, err := NewFileStore(path).GetToken("wx1", TokenAccessToken)
+	if err != nil || token != "token1" {
+		t.Errorf("reopened GetToken = %q, %v", token, err)
+	}
+}
+
+func TestFileStoreShared(t *testing.T) {
+	dir, err := ioutil.TempDir("", "store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "tokens.json")
+
+	//两个FileStore模拟共享同一文件的两个进程，并发写入不应丢失其他进程的令牌
+	stores := []*FileStore{NewFileStore(path), NewFileStore(path)}
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := stores[i%2].SetToken(fmt.Sprintf("wx%d", i), TokenAccessToken, "token", 0); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	for i := 0; i < 20; i++ {
+		if _, _, err := stores[0].GetToken(fmt.Sprintf("wx%d", i), TokenAccessToken); err != nil {
+			t.Errorf("wx%d: %v", i, err)
+		}
+	}
+}
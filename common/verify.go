@@ -0,0 +1,147 @@
+// Copyright 2020 wei_193 Author. All Rights Reserved.
+//
+// 回调签名校验
+
+package common
+
+import (
+	"crypto/subtle"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+)
+
+//DefaultTimestampSkew 回调timestamp与本地时间允许的默认偏差
+const DefaultTimestampSkew = 5 * time.Minute
+
+//回调校验错误
+var (
+	ErrSignatureMismatch = errors.New("signature mismatch")
+	ErrTimestampInvalid  = errors.New("timestamp out of allowed window")
+	ErrNonceReplayed     = errors.New("nonce already used")
+)
+
+//NonceCache 记录已使用的nonce
+type NonceCache interface {
+	//Seen 记录key，key在ttl内已出现过时返回true
+	Seen(key string, ttl time.Duration) (bool, error)
+	//Forget 删除key的记录，使相同的请求可以再次通过
+	Forget(key string) error
+}
+
+//MemoryNonceCache 内存nonce缓存，过期的记录在写入时定期清理
+type MemoryNonceCache struct {
+	lock    sync.Mutex
+	items   map[string]time.Time
+	inserts int
+}
+
+//NewMemoryNonceCache 创建内存nonce缓存
+func NewMemoryNonceCache() *MemoryNonceCache {
+	return &MemoryNonceCache{items: make(map[string]time.Time)}
+}
+
+//Seen 记录key，key在ttl内已出现过时返回true
+func (m *MemoryNonceCache) Seen(key string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.items == nil {
+		m.items = make(map[string]time.Time)
+	}
+	if expires, ok := m.items[key]; ok && now.Before(expires) {
+		return true, nil
+	}
+	m.items[key] = now.Add(ttl)
+	m.inserts++
+	if m.inserts%1024 == 0 {
+		for k, expires := range m.items {
+			if !now.Before(expires) {
+				delete(m.items, k)
+			}
+		}
+	}
+	return false, nil
+}
+
+//Forget 删除key的记录
+func (m *MemoryNonceCache) Forget(key string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	delete(m.items, key)
+	return nil
+}
+
+//Verifier 回调签名校验
+//依次检查签名、timestamp偏差和nonce是否重复
+type Verifier struct {
+	//Skew 允许的timestamp偏差，0表示DefaultTimestampSkew，小于0表示不检查
+	Skew time.Duration
+	//Nonces nonce缓存，为空时不检查重放
+	Nonces NonceCache
+	//Now 当前时间，为空时使用time.Now
+	Now func() time.Time
+}
+
+//NewVerifier 创建使用默认偏差和内存nonce缓存的校验器
+func NewVerifier() *Verifier {
+	return &Verifier{Nonces: NewMemoryNonceCache()}
+}
+
+//Verify 校验回调，expected为按参数计算的签名
+func (v *Verifier) Verify(signature, expected, timestamp, nonce string) error {
+	if !EqualSignature(signature, expected) {
+		return ErrSignatureMismatch
+	}
+	skew := v.Skew
+	if skew == 0 {
+		skew = DefaultTimestampSkew
+	}
+	if skew > 0 {
+		ts, err := strconv.ParseInt(timestamp, 10, 64)
+		if err != nil {
+			return ErrTimestampInvalid
+		}
+		now := time.Now
+		if v.Now != nil {
+			now = v.Now
+		}
+		d := now().Sub(time.Unix(ts, 0))
+		if d > skew || d < -skew {
+			return ErrTimestampInvalid
+		}
+	}
+	if v.Nonces == nil {
+		return nil
+	}
+	ttl := 2 * skew
+	if ttl <= 0 {
+		ttl = 2 * DefaultTimestampSkew
+	}
+	seen, err := v.Nonces.Seen(nonceKey(signature, timestamp, nonce), ttl)
+	if err != nil {
+		return err
+	}
+	if seen {
+		return ErrNonceReplayed
+	}
+	return nil
+}
+
+//Release 释放已记录的nonce，回调处理失败需要微信重试时调用，重试的请求可以再次通过校验
+func (v *Verifier) Release(signature, timestamp, nonce string) error {
+	if v.Nonces == nil {
+		return nil
+	}
+	return v.Nonces.Forget(nonceKey(signature, timestamp, nonce))
+}
+
+func nonceKey(signature, timestamp, nonce string) string {
+	return timestamp + ":" + nonce + ":" + signature
+}
+
+//EqualSignature 以固定时间比较签名
+func EqualSignature(signature, expected string) bool {
+	return subtle.ConstantTimeCompare([]byte(signature), []byte(expected)) == 1
+}
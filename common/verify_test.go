@@ -0,0 +1,70 @@
+package common
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestVerifier(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	v := NewVerifier()
+	v.Now = func() time.Time { return now }
+	ts := strconv.FormatInt(now.Unix(), 10)
+
+	if err := v.Verify("bad", "sign", ts, "n1"); err != ErrSignatureMismatch {
+		t.Errorf("mismatch err = %v", err)
+	}
+	if err := v.Verify("sign", "sign", ts, "n1"); err != nil {
+		t.Fatalf("valid err = %v", err)
+	}
+	if err := v.Verify("sign", "sign", ts, "n1"); err != ErrNonceReplayed {
+		t.Errorf("replay err = %v", err)
+	}
+	if err := v.Verify("sign", "sign", ts, "n2"); err != nil {
+		t.Errorf("new nonce err = %v", err)
+	}
+	if err := v.Release("sign", ts, "n1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := v.Verify("sign", "sign", ts, "n1"); err != nil {
+		t.Errorf("released nonce err = %v", err)
+	}
+
+	old := strconv.FormatInt(now.Add(-DefaultTimestampSkew-time.Second).Unix(), 10)
+	if err := v.Verify("sign", "sign", old, "n3"); err != ErrTimestampInvalid {
+		t.Errorf("old timestamp err = %v", err)
+	}
+	future := strconv.FormatInt(now.Add(DefaultTimestampSkew+time.Second).Unix(), 10)
+	if err := v.Verify("sign", "sign", future, "n3"); err != ErrTimestampInvalid {
+		t.Errorf("future timestamp err = %v", err)
+	}
+	if err := v.Verify("sign", "sign", "abc", "n3"); err != ErrTimestampInvalid {
+		t.Errorf("invalid timestamp err = %v", err)
+	}
+
+	v.Skew = -1
+	if err := v.Verify("sign", "sign", old, "n3"); err != nil {
+		t.Errorf("skew disabled err = %v", err)
+	}
+}
+
+func TestMemoryNonceCache(t *testing.T) {
+	m := NewMemoryNonceCache()
+	if seen, _ := m.Seen("a", time.Hour); seen {
+		t.Error("first Seen = true")
+	}
+	if seen, _ := m.Seen("a", time.Hour); !seen {
+		t.Error("second Seen = false")
+	}
+	if err := m.Forget("a"); err != nil {
+		t.Fatal(err)
+	}
+	if seen, _ := m.Seen("a", time.Hour); seen {
+		t.Error("forgotten key reported as seen")
+	}
+	m.Seen("b", -time.Second)
+	if seen, _ := m.Seen("b", time.Hour); seen {
+		t.Error("expired key reported as seen")
+	}
+}
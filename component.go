@@ -1,15 +1,15 @@
 package component
 
 import (
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"encoding/xml"
 	"errors"
-	"log"
-	"strings"
+	"sync"
 	"time"
 
-	"github.com/wei193/component/wechat"
+	"github.com/wei193/component/common"
 )
 
 //Component 第三平台信息
@@ -22,12 +22,22 @@ type Component struct {
 	ComponentAccessToken  string
 	AccessTokenExpires    int64
 	AESKey                []byte
+	Store                 common.TokenStore
+	Locker                common.Locker
+	Client                *common.Client
+	//Verifier 回调校验，为空时检查5分钟内的timestamp并在内存中记录nonce
+	Verifier *common.Verifier
+
+	tokenLock       sync.RWMutex
+	verifierOnce    sync.Once
+	defaultVerifier *common.Verifier
 }
 
 // XEncryptMsg 消息
 type XEncryptMsg struct {
-	AppID   string `xml:"AppId"`
-	Encrypt string `xml:"Encrypt"`
+	AppID      string `xml:"AppId"`
+	ToUserName string `xml:"ToUserName"`
+	Encrypt    string `xml:"Encrypt"`
 }
 
 //XCEvent ticket协议和推送授权相关通知
@@ -40,6 +50,12 @@ type XCEvent struct {
 	AuthorizationCode            string `xml:"AuthorizationCode"`
 	AuthorizationCodeExpiredTime int    `xml:"AuthorizationCodeExpiredTime"`
 	PreAuthCode                  string `xml:"PreAuthCode"`
+	//快速注册小程序结果，InfoType为notify_third_fasteregister
+	FastRegisterAppid  string             `xml:"appid"`
+	FastRegisterStatus int                `xml:"status"`
+	FastRegisterCode   string             `xml:"auth_code"`
+	FastRegisterMsg    string             `xml:"msg"`
+	FastRegisterInfo   *JFastRegisterInfo `xml:"info"`
 }
 
 //JPreAuthCode 预授权码
@@ -77,22 +93,30 @@ func NewComponent(appid, appsecret, token, aeskey, verifyticket, accesstoken str
 
 //GetPreAuthCode 获取预授权码
 func (c *Component) GetPreAuthCode() (authcode JPreAuthCode, err error) {
+	return c.GetPreAuthCodeContext(context.Background())
+}
+
+//GetPreAuthCodeContext 同GetPreAuthCode，使用ctx控制请求的取消和超时
+func (c *Component) GetPreAuthCodeContext(ctx context.Context) (authcode JPreAuthCode, err error) {
 	type st struct {
 		ComponentAppid string `json:"component_appid"`
 	}
 	d := st{
 		ComponentAppid: c.ComponentAppid,
 	}
-	req, err := createRequset("https://api.weixin.qq.com/cgi-bin/component/api_create_preauthcode?component_access_token="+c.ComponentAccessToken,
+	accessToken, err := c.TokenContext(ctx)
+	if err != nil {
+		return authcode, err
+	}
+	req, err := createRequset(ctx, "https://api.weixin.qq.com/cgi-bin/component/api_create_preauthcode?component_access_token="+accessToken,
 		"POST", nil, d)
 	if err != nil {
 		return authcode, err
 	}
-	res, err := requsetJosn(req)
+	res, err := c.requsetJosn(req)
 	if err != nil {
 		return authcode, err
 	}
-	log.Println(string(res))
 
 	err = json.Unmarshal(res, &authcode)
 	if err != nil {
@@ -103,38 +127,50 @@ func (c *Component) GetPreAuthCode() (authcode JPreAuthCode, err error) {
 
 //AuthEventTicket 授权事件接收处理
 func (c *Component) AuthEventTicket(msg, signature, timestamp, nonce string) (event *XCEvent, err error) {
-	isok := c.CheckSignature(signature, msg, timestamp, nonce)
-	if !isok {
-		return nil, errors.New("Check Signature Error")
-	}
 	if len(msg) == 0 {
 		return nil, errors.New("Msg Error")
 	}
-	result, err := c.MsgDecrypt(msg)
+	err = c.VerifySignature(signature, msg, timestamp, nonce)
 	if err != nil {
-		return nil, errors.New("Msg Decrypt Error")
+		return nil, err
 	}
-
-	s := strings.Index(result, "<")
-	e := strings.LastIndex(result, ">")
-	if s == -1 || e == -1 {
-		return nil, errors.New("Msg Font Error")
+	result, err := c.MsgDecrypt(msg)
+	if err != nil {
+		return nil, err
 	}
 
 	event = new(XCEvent)
-	err = xml.Unmarshal([]byte(result[s:e+1]), event)
+	err = xml.Unmarshal([]byte(result), event)
 	if err != nil {
 		return nil, errors.New("xml Unmarshal Error")
 	}
 	switch event.InfoType {
-	case "component_verify_ticket":
-		c.ComponentVerifyTicket = event.ComponentVerifyTicket
+	case InfoTypeVerifyTicket:
+		err = c.SetVerifyTicket(event.ComponentVerifyTicket)
+		if err != nil {
+			c.ReleaseNonce(signature, timestamp, nonce)
+			return event, err
+		}
 	}
 	return event, nil
 }
 
 //GetComponentAccessToken 获取第三方AccessToken
 func (c *Component) GetComponentAccessToken() (token *JComponenAccessToken, err error) {
+	return c.GetComponentAccessTokenContext(context.Background())
+}
+
+//GetComponentAccessTokenContext 同GetComponentAccessToken，使用ctx控制请求的取消和超时
+func (c *Component) GetComponentAccessTokenContext(ctx context.Context) (token *JComponenAccessToken, err error) {
+	return c.RefreshTokenContext(ctx)
+}
+
+//requsetComponentAccessToken 请求新的component_access_token
+func (c *Component) requsetComponentAccessToken(ctx context.Context) (token *JComponenAccessToken, err error) {
+	ticket, err := c.verifyTicket()
+	if err != nil {
+		return nil, err
+	}
 	type st struct {
 		ComponentAppid        string `json:"component_appid"`
 		ComponentAppsecret    string `json:"component_appsecret"`
@@ -143,34 +179,34 @@ func (c *Component) GetComponentAccessToken() (token *JComponenAccessToken, err
 	d := st{
 		ComponentAppid:        c.ComponentAppid,
 		ComponentAppsecret:    c.ComponentAppsecret,
-		ComponentVerifyTicket: c.ComponentVerifyTicket,
+		ComponentVerifyTicket: ticket,
 	}
 
-	req, err := createRequset("https://api.weixin.qq.com/cgi-bin/component/api_component_token",
+	req, err := createRequset(ctx, "https://api.weixin.qq.com/cgi-bin/component/api_component_token",
 		"POST", nil, d)
 	if err != nil {
 		return nil, err
 	}
-	res, err := requsetJosn(req)
+	res, err := c.requsetJosn(req)
 	if err != nil {
 		return nil, err
 	}
 
-	log.Println(string(res))
-
 	token = new(JComponenAccessToken)
 	err = json.Unmarshal(res, token)
 	if err != nil {
 		return nil, err
 	}
-
-	c.ComponentAccessToken = token.ComponentAccessToken
-	c.AccessTokenExpires = time.Now().Unix() + int64(token.ExpiresIn)
 	return token, nil
 }
 
 //QueryAuth 使用授权码换取公众号或小程序的接口调用凭据和授权信息
 func (c *Component) QueryAuth(code string) (authorizer *Authorizer, err error) {
+	return c.QueryAuthContext(context.Background(), code)
+}
+
+//QueryAuthContext 同QueryAuth，使用ctx控制请求的取消和超时
+func (c *Component) QueryAuthContext(ctx context.Context, code string) (authorizer *Authorizer, err error) {
 	type st struct {
 		ComponentAppid    string `json:"component_appid"`
 		AuthorizationCode string `json:"authorization_code"`
@@ -179,31 +215,36 @@ func (c *Component) QueryAuth(code string) (authorizer *Authorizer, err error) {
 		ComponentAppid:    c.ComponentAppid,
 		AuthorizationCode: code,
 	}
-	req, err := createRequset("https://api.weixin.qq.com/cgi-bin/component/api_query_auth?component_access_token="+c.ComponentAccessToken,
+	accessToken, err := c.TokenContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	req, err := createRequset(ctx, "https://api.weixin.qq.com/cgi-bin/component/api_query_auth?component_access_token="+accessToken,
 		"POST", nil, d)
 	if err != nil {
 		return nil, err
 	}
-	res, err := requsetJosn(req)
+	res, err := c.requsetJosn(req)
 	if err != nil {
 		return nil, err
 	}
 
-	log.Println(string(res))
 	var auth JAuthorizer
 	err = json.Unmarshal(res, &auth)
 	if err != nil {
 		return nil, err
 	}
 
-	authorizer = &Authorizer{
-		Component: c,
-		Wechat: &wechat.Wechat{
-			Appid:              auth.AuthorizationInfo.AuthorizerAppid,
-			AccessToken:        auth.AuthorizationInfo.AuthorizerAccessToken,
-			AccessTokenExpires: time.Now().Unix() + int64(auth.AuthorizationInfo.ExpiresIn),
-		},
-		AuthorizerRefreshToken: auth.AuthorizationInfo.AuthorizerRefreshToken,
+	info := auth.AuthorizationInfo
+	authorizer, err = c.NewAuthorizer(info.AuthorizerAppid, info.AuthorizerAccessToken,
+		time.Now().Unix()+int64(info.ExpiresIn), info.AuthorizerRefreshToken)
+	if err != nil {
+		return nil, err
+	}
+	authorizer.Scopes = NewScopeSet(info.FuncInfo)
+	err = authorizer.saveTokens()
+	if err != nil {
+		return nil, err
 	}
 	return authorizer, nil
 }
@@ -0,0 +1,152 @@
+package component
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/wei193/component/common"
+)
+
+//verifyTicketExpires component_verify_ticket有效期
+const verifyTicketExpires = 12 * 60 * 60
+
+//errNoVerifyTicket 未收到component_verify_ticket
+var errNoVerifyTicket = errors.New("component_verify_ticket is empty")
+
+//Token 获取有效的component_access_token
+//令牌即将过期时自动刷新，并发调用只会触发一次刷新请求
+func (c *Component) Token() (string, error) {
+	return c.TokenContext(context.Background())
+}
+
+//TokenContext 同Token，使用ctx控制请求的取消和超时
+func (c *Component) TokenContext(ctx context.Context) (string, error) {
+	c.tokenLock.RLock()
+	token, expires := c.ComponentAccessToken, c.AccessTokenExpires
+	c.tokenLock.RUnlock()
+	if common.TokenValid(token, expires) {
+		return token, nil
+	}
+
+	c.tokenLock.Lock()
+	defer c.tokenLock.Unlock()
+	if common.TokenValid(c.ComponentAccessToken, c.AccessTokenExpires) {
+		return c.ComponentAccessToken, nil
+	}
+	unlock, err := common.LockToken(c.Locker, c.ComponentAppid, common.TokenComponentAccessToken)
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+
+	token, expires, err = c.loadToken(c.ComponentAppid, common.TokenComponentAccessToken)
+	if err != nil {
+		return "", err
+	}
+	if common.TokenValid(token, expires) {
+		c.ComponentAccessToken, c.AccessTokenExpires = token, expires
+		return token, nil
+	}
+
+	t, err := c.requsetComponentAccessToken(ctx)
+	if err != nil {
+		return "", err
+	}
+	err = c.setComponentAccessToken(t)
+	if err != nil {
+		return "", err
+	}
+	return t.ComponentAccessToken, nil
+}
+
+//RefreshToken 强制刷新component_access_token
+//获取刷新锁后若令牌存储中已有其他实例刷新的令牌则直接使用
+func (c *Component) RefreshToken() (token *JComponenAccessToken, err error) {
+	return c.RefreshTokenContext(context.Background())
+}
+
+//RefreshTokenContext 同RefreshToken，使用ctx控制请求的取消和超时
+func (c *Component) RefreshTokenContext(ctx context.Context) (token *JComponenAccessToken, err error) {
+	c.tokenLock.Lock()
+	defer c.tokenLock.Unlock()
+	unlock, err := common.LockToken(c.Locker, c.ComponentAppid, common.TokenComponentAccessToken)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	accessToken, expires, err := c.loadToken(c.ComponentAppid, common.TokenComponentAccessToken)
+	if err != nil {
+		return nil, err
+	}
+	if common.TokenValid(accessToken, expires) && accessToken != c.ComponentAccessToken {
+		c.ComponentAccessToken, c.AccessTokenExpires = accessToken, expires
+		return &JComponenAccessToken{
+			ComponentAccessToken: accessToken,
+			ExpiresIn:            int(expires - time.Now().Unix()),
+		}, nil
+	}
+
+	token, err = c.requsetComponentAccessToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+	err = c.setComponentAccessToken(token)
+	if err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+//setComponentAccessToken 保存令牌及过期时间
+func (c *Component) setComponentAccessToken(t *JComponenAccessToken) error {
+	c.ComponentAccessToken = t.ComponentAccessToken
+	c.AccessTokenExpires = time.Now().Unix() + int64(t.ExpiresIn)
+	return c.saveToken(c.ComponentAppid, common.TokenComponentAccessToken,
+		c.ComponentAccessToken, c.AccessTokenExpires)
+}
+
+//SetVerifyTicket 保存component_verify_ticket
+func (c *Component) SetVerifyTicket(ticket string) error {
+	c.tokenLock.Lock()
+	defer c.tokenLock.Unlock()
+	c.ComponentVerifyTicket = ticket
+	return c.saveToken(c.ComponentAppid, common.TokenComponentVerifyTicket,
+		ticket, time.Now().Unix()+verifyTicketExpires)
+}
+
+//verifyTicket 获取component_verify_ticket，优先使用令牌存储中的值
+func (c *Component) verifyTicket() (string, error) {
+	ticket, _, err := c.loadToken(c.ComponentAppid, common.TokenComponentVerifyTicket)
+	if err != nil {
+		return "", err
+	}
+	if ticket != "" {
+		c.ComponentVerifyTicket = ticket
+	}
+	if c.ComponentVerifyTicket == "" {
+		return "", errNoVerifyTicket
+	}
+	return c.ComponentVerifyTicket, nil
+}
+
+//loadToken 从令牌存储读取，未设置存储或不存在时返回空
+func (c *Component) loadToken(appid string, kind common.TokenKind) (string, int64, error) {
+	if c.Store == nil {
+		return "", 0, nil
+	}
+	token, expires, err := c.Store.GetToken(appid, kind)
+	if err == common.ErrTokenNotFound {
+		return "", 0, nil
+	}
+	return token, expires, err
+}
+
+//saveToken 写入令牌存储
+func (c *Component) saveToken(appid string, kind common.TokenKind, token string, expires int64) error {
+	if c.Store == nil {
+		return nil
+	}
+	return c.Store.SetToken(appid, kind, token, expires)
+}
@@ -0,0 +1,47 @@
+package component_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/wei193/component/wechattest"
+)
+
+func TestComponentTokenConcurrent(t *testing.T) {
+	srv := wechattest.NewServer()
+	defer srv.Close()
+	c, err := srv.Component(wechattest.ComponentAppid, wechattest.Appsecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const n = 20
+	var (
+		wg     sync.WaitGroup
+		start  = make(chan struct{})
+		tokens = make([]string, n)
+		errs   = make([]error, n)
+	)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			tokens[i], errs[i] = c.Token()
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	for i := 0; i < n; i++ {
+		if errs[i] != nil {
+			t.Fatal(errs[i])
+		}
+		if tokens[i] == "" || tokens[i] != tokens[0] {
+			t.Errorf("token[%d] = %q, want %q", i, tokens[i], tokens[0])
+		}
+	}
+	if calls := srv.Calls("/cgi-bin/component/api_component_token"); calls != 1 {
+		t.Errorf("api_component_token calls = %d, want 1", calls)
+	}
+}
@@ -0,0 +1,171 @@
+package component
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"github.com/wei193/component/common"
+)
+
+//CodeType 企业代码类型
+type CodeType int
+
+//企业代码类型
+const (
+	CodeTypeCreditCode   CodeType = 1 //统一社会信用代码（18位）
+	CodeTypeOrganization CodeType = 2 //组织机构代码（9位xxxxxxxx-x）
+	CodeTypeLicense      CodeType = 3 //营业执照注册号（15位）
+)
+
+//JFastRegisterInfo 快速注册小程序的企业信息
+type JFastRegisterInfo struct {
+	//Name 企业名称
+	Name string `json:"name" xml:"name"`
+	//Code 企业代码
+	Code     string   `json:"code" xml:"code"`
+	CodeType CodeType `json:"code_type" xml:"code_type"`
+	//LegalPersonaWechat 法人微信号
+	LegalPersonaWechat string `json:"legal_persona_wechat" xml:"legal_persona_wechat"`
+	//LegalPersonaName 法人姓名，需与法人微信号绑定的身份证姓名一致
+	LegalPersonaName string `json:"legal_persona_name" xml:"legal_persona_name"`
+	//ComponentPhone 第三方联系电话
+	ComponentPhone string `json:"component_phone,omitempty" xml:"component_phone"`
+}
+
+//NewFastRegisterInfo 生成快速注册小程序的企业信息
+func NewFastRegisterInfo(name, code string, codeType CodeType, legalPersonaWechat, legalPersonaName string) JFastRegisterInfo {
+	return JFastRegisterInfo{
+		Name:               name,
+		Code:               code,
+		CodeType:           codeType,
+		LegalPersonaWechat: legalPersonaWechat,
+		LegalPersonaName:   legalPersonaName,
+	}
+}
+
+//Validate 检查必填信息
+func (info JFastRegisterInfo) Validate() error {
+	switch {
+	case info.Name == "":
+		return errors.New("fastregister: name is empty")
+	case info.Code == "":
+		return errors.New("fastregister: code is empty")
+	case info.CodeType < CodeTypeCreditCode || info.CodeType > CodeTypeLicense:
+		return fmt.Errorf("fastregister: invalid code_type %d", info.CodeType)
+	case info.LegalPersonaWechat == "":
+		return errors.New("fastregister: legal_persona_wechat is empty")
+	case info.LegalPersonaName == "":
+		return errors.New("fastregister: legal_persona_name is empty")
+	}
+	return nil
+}
+
+//快速注册任务状态，查询任务时以errcode返回
+const (
+	FastRegisterNotFound   = 89250 //未找到该任务
+	FastRegisterFaceVerify = 89251 //待法人人脸核身校验
+	FastRegisterChecking   = 89252 //法人&企业信息一致性校验中
+)
+
+//JFastRegisterStatus 快速注册任务状态
+type JFastRegisterStatus struct {
+	Errcode int
+	Errmsg  string
+}
+
+//Pending 任务是否仍在进行中
+func (s *JFastRegisterStatus) Pending() bool {
+	return s.Errcode == FastRegisterFaceVerify || s.Errcode == FastRegisterChecking
+}
+
+//FastRegisterWeapp 创建快速注册小程序任务，注册结果通过notify_third_fasteregister事件推送
+func (c *Component) FastRegisterWeapp(info JFastRegisterInfo) error {
+	return c.FastRegisterWeappContext(context.Background(), info)
+}
+
+//FastRegisterWeappContext 同FastRegisterWeapp，使用ctx控制请求的取消和超时
+func (c *Component) FastRegisterWeappContext(ctx context.Context, info JFastRegisterInfo) error {
+	err := info.Validate()
+	if err != nil {
+		return err
+	}
+	_, err = c.requsetFastRegister(ctx, "create", info)
+	return err
+}
+
+//SearchFastRegisterWeapp 查询快速注册任务状态
+//任务未找到或仍在校验中时以状态返回，其他错误返回*common.APIError
+func (c *Component) SearchFastRegisterWeapp(name, legalPersonaWechat, legalPersonaName string) (status *JFastRegisterStatus, err error) {
+	return c.SearchFastRegisterWeappContext(context.Background(), name, legalPersonaWechat, legalPersonaName)
+}
+
+//SearchFastRegisterWeappContext 同SearchFastRegisterWeapp，使用ctx控制请求的取消和超时
+func (c *Component) SearchFastRegisterWeappContext(ctx context.Context, name, legalPersonaWechat, legalPersonaName string) (status *JFastRegisterStatus, err error) {
+	type st struct {
+		Name               string `json:"name"`
+		LegalPersonaWechat string `json:"legal_persona_wechat"`
+		LegalPersonaName   string `json:"legal_persona_name"`
+	}
+	_, err = c.requsetFastRegister(ctx, "search", st{name, legalPersonaWechat, legalPersonaName})
+	if e, ok := common.AsAPIError(err); ok {
+		switch e.Errcode {
+		case FastRegisterNotFound, FastRegisterFaceVerify, FastRegisterChecking:
+			return &JFastRegisterStatus{Errcode: e.Errcode, Errmsg: e.Errmsg}, nil
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &JFastRegisterStatus{Errmsg: "ok"}, nil
+}
+
+//requsetFastRegister 发送快速注册请求
+func (c *Component) requsetFastRegister(ctx context.Context, action string, d interface{}) ([]byte, error) {
+	accessToken, err := c.TokenContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	req, err := createRequset(ctx, "https://api.weixin.qq.com/cgi-bin/component/fastregisterweapp?action="+action+
+		"&component_access_token="+url.QueryEscape(accessToken), "POST", nil, d)
+	if err != nil {
+		return nil, err
+	}
+	return c.requsetJosn(req)
+}
+
+//FastRegisterError 快速注册失败
+type FastRegisterError struct {
+	Status int
+	Msg    string
+}
+
+func (e *FastRegisterError) Error() string {
+	return fmt.Sprintf("fastregister failed: status %d %s", e.Status, e.Msg)
+}
+
+//FastRegisterErr 快速注册结果，注册成功时返回nil
+func (event *XCEvent) FastRegisterErr() error {
+	if event.InfoType != InfoTypeFastRegister {
+		return errors.New("not a fastregister event")
+	}
+	if event.FastRegisterStatus != 0 {
+		return &FastRegisterError{Status: event.FastRegisterStatus, Msg: event.FastRegisterMsg}
+	}
+	return nil
+}
+
+//FastRegisterAuthorizer 使用注册成功事件中的auth_code换取新小程序的授权方
+func (c *Component) FastRegisterAuthorizer(event *XCEvent) (authorizer *Authorizer, err error) {
+	return c.FastRegisterAuthorizerContext(context.Background(), event)
+}
+
+//FastRegisterAuthorizerContext 同FastRegisterAuthorizer，使用ctx控制请求的取消和超时
+func (c *Component) FastRegisterAuthorizerContext(ctx context.Context, event *XCEvent) (authorizer *Authorizer, err error) {
+	err = event.FastRegisterErr()
+	if err != nil {
+		return nil, err
+	}
+	return c.QueryAuthContext(ctx, event.FastRegisterCode)
+}
@@ -0,0 +1,86 @@
+package component_test
+
+import (
+	"encoding/xml"
+	"testing"
+
+	"github.com/wei193/component"
+	"github.com/wei193/component/wechattest"
+)
+
+func TestFastRegister(t *testing.T) {
+	srv := wechattest.NewServer()
+	defer srv.Close()
+	c, err := srv.Component(wechattest.ComponentAppid, wechattest.Appsecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info := component.NewFastRegisterInfo("企业", "91110000000000000X", component.CodeTypeCreditCode, "legal_wx", "张三")
+	if err = c.FastRegisterWeapp(component.JFastRegisterInfo{Name: "企业"}); err == nil {
+		t.Error("FastRegisterWeapp with missing info succeeded")
+	}
+	if err = c.FastRegisterWeapp(info); err != nil {
+		t.Fatal(err)
+	}
+	reqs := srv.Requests()
+	if last := reqs[len(reqs)-1]; last.Path != "/cgi-bin/component/fastregisterweapp" || last.Query["action"] != "create" {
+		t.Errorf("request = %s %v", last.Path, last.Query)
+	}
+
+	srv.Inject("/cgi-bin/component/fastregisterweapp", wechattest.Fault{Errcode: component.FastRegisterFaceVerify, Errmsg: "wait face verify"})
+	status, err := c.SearchFastRegisterWeapp(info.Name, info.LegalPersonaWechat, info.LegalPersonaName)
+	if err != nil || !status.Pending() {
+		t.Errorf("SearchFastRegisterWeapp = %+v, %v", status, err)
+	}
+	srv.Inject("/cgi-bin/component/fastregisterweapp", wechattest.Fault{Errcode: 89249, Errmsg: "task running"})
+	if _, err = c.SearchFastRegisterWeapp(info.Name, info.LegalPersonaWechat, info.LegalPersonaName); err == nil {
+		t.Error("SearchFastRegisterWeapp with errcode 89249 succeeded")
+	}
+}
+
+func TestFastRegisterEvent(t *testing.T) {
+	data := `<xml>
+	<AppId><![CDATA[wx0000000000000002]]></AppId>
+	<CreateTime>1535442403</CreateTime>
+	<InfoType><![CDATA[notify_third_fasteregister]]></InfoType>
+	<appid><![CDATA[wxnew]]></appid>
+	<status>0</status>
+	<auth_code><![CDATA[AUTH_CODE]]></auth_code>
+	<msg><![CDATA[OK]]></msg>
+	<info>
+		<name><![CDATA[企业]]></name>
+		<code><![CDATA[91110000000000000X]]></code>
+		<code_type>1</code_type>
+		<legal_persona_wechat><![CDATA[legal_wx]]></legal_persona_wechat>
+		<legal_persona_name><![CDATA[张三]]></legal_persona_name>
+		<component_phone><![CDATA[1234567]]></component_phone>
+	</info>
+</xml>`
+	var event component.XCEvent
+	if err := xml.Unmarshal([]byte(data), &event); err != nil {
+		t.Fatal(err)
+	}
+	if event.AppID != wechattest.ComponentAppid || event.FastRegisterAppid != "wxnew" || event.FastRegisterCode != "AUTH_CODE" ||
+		event.FastRegisterInfo == nil || event.FastRegisterInfo.CodeType != component.CodeTypeCreditCode {
+		t.Fatalf("event = %+v", event)
+	}
+
+	srv := wechattest.NewServer()
+	defer srv.Close()
+	c, err := srv.Component(wechattest.ComponentAppid, wechattest.Appsecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = c.FastRegisterAuthorizer(&event); err != nil {
+		t.Fatal(err)
+	}
+	if n := srv.Calls("/cgi-bin/component/api_query_auth"); n != 1 {
+		t.Errorf("query auth calls = %d", n)
+	}
+
+	event.FastRegisterStatus = 86004
+	if _, ok := event.FastRegisterErr().(*component.FastRegisterError); !ok {
+		t.Errorf("FastRegisterErr = %v", event.FastRegisterErr())
+	}
+}
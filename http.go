@@ -2,13 +2,13 @@ package component
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
-	"errors"
-	"io/ioutil"
 	"net/http"
 	"net/url"
 	"strings"
-	"time"
+
+	"github.com/wei193/component/common"
 )
 
 //JSONError  微信错误
@@ -17,29 +17,33 @@ type JSONError struct {
 	Errmsg  string `json:"errmsg"`
 }
 
-func requsetJosn(req *http.Request) ([]byte, error) {
-	client := &http.Client{Timeout: 60 * time.Second}
-	// b, _ := httputil.DumpRequest(req, true)
-	// log.Println(string(b))
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
+//requsetJosn 发送带component_access_token的请求
+//令牌无效或过期时强制刷新一次并重放请求，系统繁忙时按重试策略重试
+func (c *Component) requsetJosn(req *http.Request) ([]byte, error) {
+	req = req.WithContext(common.WithLogFields(req.Context(), common.F("component_appid", c.ComponentAppid)))
+	return c.APIClient().RequsetJSON(req, "component_access_token", c.refreshToken)
+}
+
+//APIClient 请求客户端，未设置Client时使用common.DefaultClient
+//通过NewAuthorizer创建的授权方共用该客户端
+func (c *Component) APIClient() *common.Client {
+	if c.Client != nil {
+		return c.Client
 	}
-	defer resp.Body.Close()
-	res, err := ioutil.ReadAll(resp.Body)
+	return common.DefaultClient
+}
+
+//refreshToken 强制刷新component_access_token，作为common.TokenRefresher使用
+func (c *Component) refreshToken(ctx context.Context) (string, error) {
+	token, err := c.RefreshTokenContext(ctx)
 	if err != nil {
-		return res, err
-	}
-	var errcode JSONError
-	err = json.Unmarshal(res, &errcode)
-	if err == nil && errcode.Errcode != 0 {
-		return res, errors.New(string(res))
+		return "", err
 	}
-	return res, err
+	return token.ComponentAccessToken, nil
 }
 
 //createRequset 生成请求requset参数
-func createRequset(surl, method string, p map[string]string, d interface{}) (req *http.Request, err error) {
+func createRequset(ctx context.Context, surl, method string, p map[string]string, d interface{}) (req *http.Request, err error) {
 	buf, err := json.Marshal(d)
 	if d != nil && err != nil {
 		return nil, err
@@ -77,7 +81,7 @@ func createRequset(surl, method string, p map[string]string, d interface{}) (req
 		}
 	}
 
-	req, err = http.NewRequest(method, surl, bytes.NewReader(buf))
+	req, err = http.NewRequestWithContext(ctx, method, surl, bytes.NewReader(buf))
 	if err != nil {
 		return nil, err
 	}
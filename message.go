@@ -0,0 +1,101 @@
+package component
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/wei193/component/common"
+	"github.com/wei193/component/wechat"
+)
+
+//CDATA xml CDATA字段
+type CDATA struct {
+	Value string `xml:",cdata"`
+}
+
+//XEncryptResponse 安全模式下的被动回复消息
+type XEncryptResponse struct {
+	XMLName      xml.Name `xml:"xml"`
+	Encrypt      CDATA    `xml:"Encrypt"`
+	MsgSignature CDATA    `xml:"MsgSignature"`
+	TimeStamp    int64    `xml:"TimeStamp"`
+	Nonce        CDATA    `xml:"Nonce"`
+}
+
+//EncryptResponse 使用第三方平台的密钥加密被动回复消息，返回带签名的xml
+func (c *Component) EncryptResponse(resp *wechat.STMsgResponse) ([]byte, error) {
+	data, err := xml.Marshal(resp)
+	if err != nil {
+		return nil, err
+	}
+	encrypt, err := c.MsgEncrypt(string(data))
+	if err != nil {
+		return nil, err
+	}
+	timestamp := time.Now().Unix()
+	nonce := common.RandomStr(16, 3)
+	res := XEncryptResponse{
+		Encrypt:      CDATA{encrypt},
+		MsgSignature: CDATA{GetSignature(encrypt, c.ComponentToken, strconv.FormatInt(timestamp, 10), nonce)},
+		TimeStamp:    timestamp,
+		Nonce:        CDATA{nonce},
+	}
+	return xml.Marshal(res)
+}
+
+//DecryptRequest 校验签名并解密授权方的消息
+//signature、timestamp、nonce为URL中的msg_signature、timestamp、nonce参数
+func (c *Component) DecryptRequest(signature, timestamp, nonce string, body []byte) (req *wechat.STMsgRequest, err error) {
+	var msg XEncryptMsg
+	err = xml.Unmarshal(body, &msg)
+	if err != nil {
+		return nil, err
+	}
+	if msg.Encrypt == "" {
+		return nil, errors.New("Msg Error")
+	}
+	err = c.VerifySignature(signature, msg.Encrypt, timestamp, nonce)
+	if err != nil {
+		return nil, err
+	}
+	result, err := c.MsgDecrypt(msg.Encrypt)
+	if err != nil {
+		return nil, err
+	}
+	return wechat.DecodeRequest([]byte(result))
+}
+
+//EncryptResponse 加密回复授权方的消息
+func (a *Authorizer) EncryptResponse(resp *wechat.STMsgResponse) ([]byte, error) {
+	return a.Component.EncryptResponse(resp)
+}
+
+//ErrReceiverMismatch 消息的接收方不是该授权方
+var ErrReceiverMismatch = errors.New("message is not sent to this authorizer")
+
+//DecryptRequest 解密授权方的消息，并校验消息的接收方ToUserName为该授权方
+//UserName为空时先调用GetAuthorizerInfo获取，获取失败时返回错误
+func (a *Authorizer) DecryptRequest(signature, timestamp, nonce string, body []byte) (*wechat.STMsgRequest, error) {
+	return a.DecryptRequestContext(context.Background(), signature, timestamp, nonce, body)
+}
+
+//DecryptRequestContext 同DecryptRequest，使用ctx控制获取授权方信息请求的取消和超时
+func (a *Authorizer) DecryptRequestContext(ctx context.Context, signature, timestamp, nonce string, body []byte) (*wechat.STMsgRequest, error) {
+	req, err := a.Component.DecryptRequest(signature, timestamp, nonce, body)
+	if err != nil {
+		return nil, err
+	}
+	userName, err := a.userName(ctx)
+	if err != nil {
+		//无法校验接收方，释放nonce以便微信服务器重试
+		a.Component.ReleaseNonce(signature, timestamp, nonce)
+		return nil, err
+	}
+	if req.ToUserName != userName {
+		return nil, ErrReceiverMismatch
+	}
+	return req, nil
+}
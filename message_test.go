@@ -0,0 +1,55 @@
+package component_test
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/wei193/component"
+	"github.com/wei193/component/wechattest"
+)
+
+func TestAuthorizerDecryptRequest(t *testing.T) {
+	srv := wechattest.NewServer()
+	defer srv.Close()
+	c, err := srv.Component(wechattest.ComponentAppid, wechattest.Appsecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := c.NewAuthorizer(wechattest.Appid, srv.IssueToken(), 1<<62, "REFRESH_"+wechattest.Appid)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	decrypt := func(toUserName, nonce string) error {
+		encrypt, err := c.MsgEncrypt("<xml><ToUserName>" + toUserName + "</ToUserName><FromUserName>openid</FromUserName>" +
+			"<MsgType>text</MsgType><Content>hi</Content></xml>")
+		if err != nil {
+			t.Fatal(err)
+		}
+		body := "<xml><ToUserName>" + toUserName + "</ToUserName><Encrypt>" + encrypt + "</Encrypt></xml>"
+		sign := component.GetSignature(encrypt, c.ComponentToken, timestamp, nonce)
+		_, err = a.DecryptRequest(sign, timestamp, nonce, []byte(body))
+		return err
+	}
+
+	//获取授权方信息失败时无法校验接收方，返回错误，相同参数重试时可以再次处理
+	srv.Inject("/cgi-bin/component/api_get_authorizer_info", wechattest.Fault{Errcode: -1, Errmsg: "system error"})
+	if err = decrypt("gh_000000000001", "nonce1"); err == nil {
+		t.Error("DecryptRequest succeeded without authorizer info")
+	}
+	//UserName为空时获取授权方信息后校验接收方
+	if err = decrypt("gh_000000000001", "nonce1"); err != nil {
+		t.Errorf("retried DecryptRequest err = %v", err)
+	}
+	if a.UserName != "gh_000000000001" {
+		t.Errorf("UserName = %s", a.UserName)
+	}
+	if err = decrypt("gh_other", "nonce2"); err != component.ErrReceiverMismatch {
+		t.Errorf("DecryptRequest for other receiver err = %v", err)
+	}
+	if n := srv.Calls("/cgi-bin/component/api_get_authorizer_info"); n != 2 {
+		t.Errorf("authorizer info calls = %d, want 2", n)
+	}
+}
@@ -1,6 +1,7 @@
 package miniprogram
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"net/http"
@@ -25,6 +26,11 @@ type AccessToken struct {
 
 //GetAccessToken 获取 access_token
 func (mini *MiniProgram) GetAccessToken() (err error) {
+	return mini.GetAccessTokenContext(context.Background())
+}
+
+//GetAccessTokenContext 同GetAccessToken，使用ctx控制请求的取消和超时
+func (mini *MiniProgram) GetAccessTokenContext(ctx context.Context) (err error) {
 	if mini.Appsecret == "" {
 		return errors.New("no secret")
 	}
@@ -33,9 +39,9 @@ func (mini *MiniProgram) GetAccessToken() (err error) {
 	param["appid"] = mini.Appid
 	param["secret"] = mini.Appsecret
 
-	req, err := http.NewRequest("GET", common.Param("https://api.weixin.qq.com/cgi-bin/token", param), nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", common.Param("https://api.weixin.qq.com/cgi-bin/token", param), nil)
 
-	resBody, err := common.Requset(req)
+	resBody, err := mini.APIClient().RequsetJSON(req, "", nil)
 	if err != nil {
 		return err
 	}
@@ -44,19 +50,18 @@ func (mini *MiniProgram) GetAccessToken() (err error) {
 	if err != nil {
 		return err
 	}
-	if acc.Errcode != 0 {
-		return errors.New(acc.Errmsg)
-	}
-	mini.AccessTokenExpires = time.Now().Unix() + int64(acc.Expiresin)
-	mini.AccessToken = acc.AccessToken
-
-	return nil
+	return mini.SetAccessToken(acc.AccessToken, time.Now().Unix()+int64(acc.Expiresin))
 }
 
 //Getpaidunionid 微信用户支付以后获取用户UnionId
 func (mini *MiniProgram) Getpaidunionid(openid, transactionid, outtradeno string) (id string, err error) {
+	return mini.GetpaidunionidContext(context.Background(), openid, transactionid, outtradeno)
+}
+
+//GetpaidunionidContext 同Getpaidunionid，使用ctx控制请求的取消和超时
+func (mini *MiniProgram) GetpaidunionidContext(ctx context.Context, openid, transactionid, outtradeno string) (id string, err error) {
 	param := make(map[string]string)
-	param["access_token"] = mini.AccessToken
+	param["access_token"] = ""
 	param["openid"] = openid
 	if transactionid == "" {
 		param["transaction_id"] = transactionid
@@ -66,9 +71,9 @@ func (mini *MiniProgram) Getpaidunionid(openid, transactionid, outtradeno string
 		param["out_trade_no"] = outtradeno
 	}
 
-	req, err := http.NewRequest("GET", common.Param("https://api.weixin.qq.com/cgi-bin/token", param), nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", common.Param("https://api.weixin.qq.com/cgi-bin/token", param), nil)
 
-	resBody, err := common.Requset(req)
+	resBody, err := mini.RequsetJSON(req, 0)
 	if err != nil {
 		return "", err
 	}
@@ -82,9 +87,6 @@ func (mini *MiniProgram) Getpaidunionid(openid, transactionid, outtradeno string
 	if err != nil {
 		return "", err
 	}
-	if data.Errcode != 0 {
-		return "", errors.New(data.Errmsg)
-	}
 
 	return data.Unionid, nil
 }
@@ -100,6 +102,11 @@ type MiniSession struct {
 
 //Code2Session 通过Code获取session_key
 func (mini *MiniProgram) Code2Session(code string) (s MiniSession, err error) {
+	return mini.Code2SessionContext(context.Background(), code)
+}
+
+//Code2SessionContext 同Code2Session，使用ctx控制请求的取消和超时
+func (mini *MiniProgram) Code2SessionContext(ctx context.Context, code string) (s MiniSession, err error) {
 	if mini.Appsecret == "" {
 		return s, errors.New("no secret")
 	}
@@ -109,9 +116,9 @@ func (mini *MiniProgram) Code2Session(code string) (s MiniSession, err error) {
 	param["secret"] = mini.Appsecret
 	param["js_code"] = code
 
-	req, err := http.NewRequest("GET", common.Param("https://api.weixin.qq.com/sns/jscode2session", param), nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", common.Param("https://api.weixin.qq.com/sns/jscode2session", param), nil)
 
-	resBody, err := common.Requset(req)
+	resBody, err := mini.APIClient().RequsetJSON(req, "", nil)
 	if err != nil {
 		return s, err
 	}
@@ -119,8 +126,5 @@ func (mini *MiniProgram) Code2Session(code string) (s MiniSession, err error) {
 	if err != nil {
 		return s, err
 	}
-	if s.Errcode != 0 {
-		return s, errors.New(s.Errmsg)
-	}
 	return s, nil
 }
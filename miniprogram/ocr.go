@@ -2,8 +2,8 @@ package miniprogram
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
-	"errors"
 	"io"
 	"mime/multipart"
 	"net/http"
@@ -22,8 +22,13 @@ type BankcardInfo struct {
 
 //Bankcard 银行卡 OCR 识别
 func (mini *MiniProgram) Bankcard(media string) (info BankcardInfo, err error) {
+	return mini.BankcardContext(context.Background(), media)
+}
+
+//BankcardContext 同Bankcard，使用ctx控制请求的取消和超时
+func (mini *MiniProgram) BankcardContext(ctx context.Context, media string) (info BankcardInfo, err error) {
 	param := make(map[string]string)
-	param["access_token"] = mini.AccessToken
+	param["access_token"] = ""
 
 	file, err := os.Open(media)
 	if err != nil {
@@ -37,11 +42,11 @@ func (mini *MiniProgram) Bankcard(media string) (info BankcardInfo, err error) {
 	io.Copy(part, file)
 	writer.Close()
 
-	req, err := http.NewRequest("POST",
+	req, err := http.NewRequestWithContext(ctx, "POST",
 		common.Param("https://api.weixin.qq.com/cv/ocr/bankcard", param),
 		body)
 
-	resBody, err := common.Requset(req)
+	resBody, err := mini.RequsetJSON(req, 0)
 	if err != nil {
 		return info, err
 	}
@@ -49,23 +54,25 @@ func (mini *MiniProgram) Bankcard(media string) (info BankcardInfo, err error) {
 	if err != nil {
 		return info, err
 	}
-	if info.Errcode != 0 {
-		return info, errors.New(info.Errmsg)
-	}
 	return info, nil
 }
 
 //BankcardByURL 银行卡 OCR 识别
 func (mini *MiniProgram) BankcardByURL(url string) (info BankcardInfo, err error) {
+	return mini.BankcardByURLContext(context.Background(), url)
+}
+
+//BankcardByURLContext 同BankcardByURL，使用ctx控制请求的取消和超时
+func (mini *MiniProgram) BankcardByURLContext(ctx context.Context, url string) (info BankcardInfo, err error) {
 	param := make(map[string]string)
-	param["access_token"] = mini.AccessToken
+	param["access_token"] = ""
 	param["img_url"] = url
 
-	req, err := http.NewRequest("POST",
+	req, err := http.NewRequestWithContext(ctx, "POST",
 		common.Param("https://api.weixin.qq.com/cv/ocr/bankcard", param),
 		nil)
 
-	resBody, err := common.Requset(req)
+	resBody, err := mini.RequsetJSON(req, 0)
 	if err != nil {
 		return info, err
 	}
@@ -73,8 +80,5 @@ func (mini *MiniProgram) BankcardByURL(url string) (info BankcardInfo, err error
 	if err != nil {
 		return info, err
 	}
-	if info.Errcode != 0 {
-		return info, errors.New(info.Errmsg)
-	}
 	return info, nil
 }
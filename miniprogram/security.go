@@ -6,8 +6,8 @@ package miniprogram
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
-	"errors"
 	"io"
 	"mime/multipart"
 	"net/http"
@@ -26,8 +26,13 @@ type CheckResult struct {
 
 //ImgSecCheck 图片安全检查
 func (mini *MiniProgram) ImgSecCheck(media string) (res CheckResult, err error) {
+	return mini.ImgSecCheckContext(context.Background(), media)
+}
+
+//ImgSecCheckContext 同ImgSecCheck，使用ctx控制请求的取消和超时
+func (mini *MiniProgram) ImgSecCheckContext(ctx context.Context, media string) (res CheckResult, err error) {
 	param := make(map[string]string)
-	param["access_token"] = mini.AccessToken
+	param["access_token"] = ""
 
 	file, err := os.Open(media)
 	if err != nil {
@@ -41,11 +46,11 @@ func (mini *MiniProgram) ImgSecCheck(media string) (res CheckResult, err error)
 	io.Copy(part, file)
 	writer.Close()
 
-	req, err := http.NewRequest("POST",
+	req, err := http.NewRequestWithContext(ctx, "POST",
 		common.Param("https://api.weixin.qq.com/wxa/img_sec_check", param),
 		body)
 
-	resBody, err := common.Requset(req)
+	resBody, err := mini.RequsetJSON(req, 0)
 	if err != nil {
 		return res, err
 	}
@@ -53,16 +58,18 @@ func (mini *MiniProgram) ImgSecCheck(media string) (res CheckResult, err error)
 	if err != nil {
 		return res, err
 	}
-	if res.Errcode != 0 {
-		return res, errors.New(res.Errmsg)
-	}
 	return res, nil
 }
 
 //MediaCheckAsync 异步检查媒体是否存在违规信息
 func (mini *MiniProgram) MediaCheckAsync(url string, typ int) (res CheckResult, err error) {
+	return mini.MediaCheckAsyncContext(context.Background(), url, typ)
+}
+
+//MediaCheckAsyncContext 同MediaCheckAsync，使用ctx控制请求的取消和超时
+func (mini *MiniProgram) MediaCheckAsyncContext(ctx context.Context, url string, typ int) (res CheckResult, err error) {
 	param := make(map[string]string)
-	param["access_token"] = mini.AccessToken
+	param["access_token"] = ""
 
 	tmp := make(map[string]interface{})
 	tmp["media_url"] = url
@@ -70,10 +77,10 @@ func (mini *MiniProgram) MediaCheckAsync(url string, typ int) (res CheckResult,
 
 	rdata, _ := json.Marshal(tmp)
 
-	req, err := http.NewRequest("POST",
+	req, err := http.NewRequestWithContext(ctx, "POST",
 		common.Param("https://api.weixin.qq.com/wxa/media_check_async", param),
 		bytes.NewReader(rdata))
-	resBody, err := common.Requset(req)
+	resBody, err := mini.RequsetJSON(req, 0)
 	if err != nil {
 		return res, err
 	}
@@ -81,26 +88,28 @@ func (mini *MiniProgram) MediaCheckAsync(url string, typ int) (res CheckResult,
 	if err != nil {
 		return res, err
 	}
-	if res.Errcode != 0 {
-		return res, errors.New(res.Errmsg)
-	}
 	return res, nil
 }
 
 //MsgSecCheck 文本检查
 func (mini *MiniProgram) MsgSecCheck(content string) (res CheckResult, err error) {
+	return mini.MsgSecCheckContext(context.Background(), content)
+}
+
+//MsgSecCheckContext 同MsgSecCheck，使用ctx控制请求的取消和超时
+func (mini *MiniProgram) MsgSecCheckContext(ctx context.Context, content string) (res CheckResult, err error) {
 	param := make(map[string]string)
-	param["access_token"] = mini.AccessToken
+	param["access_token"] = ""
 
 	tmp := make(map[string]interface{})
 	tmp["content"] = content
 	rdata, _ := json.Marshal(tmp)
 
-	req, err := http.NewRequest("POST",
+	req, err := http.NewRequestWithContext(ctx, "POST",
 		common.Param("https://api.weixin.qq.com/wxa/msg_sec_check", param),
 		bytes.NewReader(rdata))
 
-	resBody, err := common.Requset(req)
+	resBody, err := mini.RequsetJSON(req, 0)
 	if err != nil {
 		return res, err
 	}
@@ -108,8 +117,5 @@ func (mini *MiniProgram) MsgSecCheck(content string) (res CheckResult, err error
 	if err != nil {
 		return res, err
 	}
-	if res.Errcode != 0 {
-		return res, errors.New(res.Errmsg)
-	}
 	return res, nil
 }
@@ -4,33 +4,94 @@ import (
 	"bytes"
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/rand"
 	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
 )
 
-//MsgDecrypt 消息解密
+//msgBlockSize 消息加解密PKCS#7填充块大小
+const msgBlockSize = 32
+
+//消息加解密错误
+var (
+	ErrInvalidPadding = errors.New("invalid PKCS#7 padding")
+	ErrInvalidMsg     = errors.New("invalid encrypted msg")
+	ErrAppidMismatch  = errors.New("msg appid mismatch")
+)
+
+//MsgDecrypt 消息解密，返回消息明文并校验appid
 func (c *Component) MsgDecrypt(data string) (result string, err error) {
 	buf, err := base64.StdEncoding.DecodeString(data)
 	if err != nil {
 		return "", err
 	}
-	d, err := AesCBCDecrypt(buf, c.AESKey)
+	d, err := DecryptMsg(c.AESKey, c.ComponentAppid, buf)
 	if err != nil {
 		return "", err
 	}
 	return string(d), nil
 }
 
-//MsgEncrypt 消息加密
+//MsgEncrypt 消息加密，返回base64编码的密文
 func (c *Component) MsgEncrypt(data string) (result string, err error) {
-	buf, err := base64.StdEncoding.DecodeString(data)
+	d, err := EncryptMsg(c.AESKey, c.ComponentAppid, []byte(data))
 	if err != nil {
 		return "", err
 	}
-	d, err := AesCBCEncrypt(buf, c.AESKey)
+	return base64.StdEncoding.EncodeToString(d), nil
+}
+
+//EncryptMsg 按微信消息加解密格式加密
+//明文为16字节随机串+4字节网络字节序的消息长度+消息+appid，以32字节为块PKCS#7填充后AES-CBC加密
+func EncryptMsg(key []byte, appid string, msg []byte) ([]byte, error) {
+	buf := make([]byte, 20, 20+len(msg)+len(appid)+msgBlockSize)
+	_, err := io.ReadFull(rand.Reader, buf[:16])
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	return base64.StdEncoding.EncodeToString(d), nil
+	binary.BigEndian.PutUint32(buf[16:20], uint32(len(msg)))
+	buf = append(buf, msg...)
+	buf = append(buf, appid...)
+	buf = PKCS7Padding(buf, msgBlockSize)
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	ciphertext := make([]byte, len(buf))
+	cipher.NewCBCEncrypter(block, key[:block.BlockSize()]).CryptBlocks(ciphertext, buf)
+	return ciphertext, nil
+}
+
+//DecryptMsg 按微信消息加解密格式解密，校验填充及appid后返回消息
+func DecryptMsg(key []byte, appid string, ciphertext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) == 0 || len(ciphertext)%block.BlockSize() != 0 {
+		return nil, ErrInvalidMsg
+	}
+	plantText := make([]byte, len(ciphertext))
+	cipher.NewCBCDecrypter(block, key[:block.BlockSize()]).CryptBlocks(plantText, ciphertext)
+	plantText, err = PKCS7UnPadding(plantText, msgBlockSize)
+	if err != nil {
+		return nil, err
+	}
+	if len(plantText) < 20 {
+		return nil, ErrInvalidMsg
+	}
+	length := binary.BigEndian.Uint32(plantText[16:20])
+	if uint64(length) > uint64(len(plantText)-20) {
+		return nil, ErrInvalidMsg
+	}
+	msg := plantText[20 : 20+length]
+	if string(plantText[20+length:]) != appid {
+		return nil, ErrAppidMismatch
+	}
+	return msg, nil
 }
 
 //AesCBCDecrypt 解密
@@ -39,18 +100,31 @@ func AesCBCDecrypt(ciphertext, key []byte) ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
+	if len(ciphertext) == 0 || len(ciphertext)%block.BlockSize() != 0 {
+		return nil, ErrInvalidMsg
+	}
 	blockModel := cipher.NewCBCDecrypter(block, key[:block.BlockSize()])
 	plantText := make([]byte, len(ciphertext))
 	blockModel.CryptBlocks(plantText, ciphertext)
-	plantText = PKCS7UnPadding(plantText, block.BlockSize())
-	return plantText, nil
+	return PKCS7UnPadding(plantText, block.BlockSize())
 }
 
-//PKCS7UnPadding PKCS7删除
-func PKCS7UnPadding(plantText []byte, blockSize int) []byte {
+//PKCS7UnPadding PKCS7删除，填充不合法时返回ErrInvalidPadding
+func PKCS7UnPadding(plantText []byte, blockSize int) ([]byte, error) {
 	length := len(plantText)
+	if length == 0 || length%blockSize != 0 {
+		return nil, ErrInvalidPadding
+	}
 	unpadding := int(plantText[length-1])
-	return plantText[:(length - unpadding)]
+	if unpadding == 0 || unpadding > blockSize {
+		return nil, ErrInvalidPadding
+	}
+	for _, b := range plantText[length-unpadding:] {
+		if int(b) != unpadding {
+			return nil, ErrInvalidPadding
+		}
+	}
+	return plantText[:(length - unpadding)], nil
 }
 
 //AesCBCEncrypt 加密
@@ -0,0 +1,105 @@
+package component
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/xml"
+	"strconv"
+	"testing"
+
+	"github.com/wei193/component/wechat"
+)
+
+const (
+	testAppid  = "wx0123456789abcdef"
+	testAesKey = "abcdefghijklmnopqrstuvwxyz0123456789ABCDEFG"
+)
+
+func newTestComponent(t *testing.T) *Component {
+	c, err := NewComponent(testAppid, "secret", "token", testAesKey, "", "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestMsgCrypt(t *testing.T) {
+	c := newTestComponent(t)
+	msg := "<xml><ToUserName><![CDATA[gh_123]]></ToUserName></xml>"
+	enc, err := c.MsgEncrypt(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dec, err := c.MsgDecrypt(enc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dec != msg {
+		t.Errorf("MsgDecrypt = %q, want %q", dec, msg)
+	}
+
+	other, _ := NewComponent("wxother", "secret", "token", testAesKey, "", "", 0)
+	if _, err = other.MsgDecrypt(enc); err != ErrAppidMismatch {
+		t.Errorf("MsgDecrypt with other appid = %v, want ErrAppidMismatch", err)
+	}
+}
+
+func TestDecryptMsgMalformed(t *testing.T) {
+	c := newTestComponent(t)
+	block, _ := aes.NewCipher(c.AESKey)
+	encrypt := func(plantText []byte) []byte {
+		out := make([]byte, len(plantText))
+		cipher.NewCBCEncrypter(block, c.AESKey[:16]).CryptBlocks(out, plantText)
+		return out
+	}
+
+	cases := map[string][]byte{
+		"zero padding":     make([]byte, 32),
+		"padding too long": bytes.Repeat([]byte{33}, 64),
+		"mixed padding":    append(bytes.Repeat([]byte{1}, 31), 2),
+	}
+	for name, plantText := range cases {
+		if _, err := DecryptMsg(c.AESKey, testAppid, encrypt(plantText)); err != ErrInvalidPadding {
+			t.Errorf("%s: err = %v, want ErrInvalidPadding", name, err)
+		}
+	}
+
+	length := PKCS7Padding(append(make([]byte, 16), 0, 0, 0xff, 0xff), msgBlockSize)
+	if _, err := DecryptMsg(c.AESKey, testAppid, encrypt(length)); err != ErrInvalidMsg {
+		t.Errorf("bad length: err = %v, want ErrInvalidMsg", err)
+	}
+	if _, err := DecryptMsg(c.AESKey, testAppid, []byte("short")); err != ErrInvalidMsg {
+		t.Errorf("short ciphertext: err = %v, want ErrInvalidMsg", err)
+	}
+}
+
+func TestEncryptResponse(t *testing.T) {
+	c := newTestComponent(t)
+	resp := &wechat.STMsgResponse{
+		ToUserName:   "openid",
+		FromUserName: "gh_123",
+		CreateTime:   1600000000,
+		MsgType:      wechat.Text,
+		Content:      "hello",
+	}
+	data, err := c.EncryptResponse(resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var env XEncryptResponse
+	if err = xml.Unmarshal(data, &env); err != nil {
+		t.Fatal(err)
+	}
+	req, err := c.DecryptRequest(env.MsgSignature.Value, strconv.FormatInt(env.TimeStamp, 10),
+		env.Nonce.Value, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.ToUserName != "openid" || req.Content != "hello" || req.MsgType != wechat.Text {
+		t.Errorf("DecryptRequest = %+v", req)
+	}
+	if _, err = c.DecryptRequest("bad", "1", "nonce", data); err == nil {
+		t.Error("DecryptRequest accepted bad signature")
+	}
+}
@@ -0,0 +1,151 @@
+package component
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wei193/component/wechat"
+)
+
+//AuthorizerLookup 按appid查找授权方
+type AuthorizerLookup func(appid string) (*Authorizer, error)
+
+//MsgHandler 授权方消息处理，返回nil时回复success
+type MsgHandler func(a *Authorizer, req *wechat.STMsgRequest) (*wechat.STMsgResponse, error)
+
+//ErrAuthorizerNotFound 授权方不存在
+var ErrAuthorizerNotFound = errors.New("authorizer not found")
+
+//MsgServer 第三方平台消息与事件接收URL的http.Handler
+//从路径中取出授权方appid，解密消息后交给对应的处理函数，并加密回复
+type MsgServer struct {
+	Component *Component
+	Lookup    AuthorizerLookup
+	//Handler 未单独设置处理函数的授权方使用的处理函数
+	Handler MsgHandler
+	//AppidFromPath 从请求路径中取出appid，默认取 /$APPID$/callback 中的$APPID$
+	AppidFromPath func(path string) string
+
+	lock     sync.RWMutex
+	handlers map[string]MsgHandler
+}
+
+//NewMsgServer 新建消息接收服务
+func (c *Component) NewMsgServer(lookup AuthorizerLookup, handler MsgHandler) *MsgServer {
+	return &MsgServer{
+		Component: c,
+		Lookup:    lookup,
+		Handler:   handler,
+		handlers:  make(map[string]MsgHandler),
+	}
+}
+
+//Handle 设置指定授权方的处理函数
+func (s *MsgServer) Handle(appid string, handler MsgHandler) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.handlers == nil {
+		s.handlers = make(map[string]MsgHandler)
+	}
+	s.handlers[appid] = handler
+}
+
+//handler 获取授权方的处理函数
+func (s *MsgServer) handler(appid string) MsgHandler {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	if h, ok := s.handlers[appid]; ok {
+		return h
+	}
+	return s.Handler
+}
+
+//ServeHTTP 处理授权方消息推送
+func (s *MsgServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	appidFromPath := s.AppidFromPath
+	if appidFromPath == nil {
+		appidFromPath = AppidFromPath
+	}
+	appid := appidFromPath(r.URL.Path)
+	if appid == "" {
+		http.NotFound(w, r)
+		return
+	}
+	authorizer, err := s.Lookup(appid)
+	if err == ErrAuthorizerNotFound || (err == nil && authorizer == nil) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, maxEventBody+1))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(body) > maxEventBody {
+		http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+		return
+	}
+	q := r.URL.Query()
+	signature, timestamp, nonce := q.Get("msg_signature"), q.Get("timestamp"), q.Get("nonce")
+	req, err := authorizer.DecryptRequestContext(r.Context(), signature, timestamp, nonce, body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	handler := s.handler(appid)
+	if handler == nil {
+		io.WriteString(w, "success")
+		return
+	}
+	resp, err := handler(authorizer, req)
+	if err != nil {
+		authorizer.Component.ReleaseNonce(signature, timestamp, nonce)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if resp == nil {
+		io.WriteString(w, "success")
+		return
+	}
+	if resp.ToUserName == "" {
+		resp.ToUserName = req.FromUserName
+	}
+	if resp.FromUserName == "" {
+		resp.FromUserName = req.ToUserName
+	}
+	if resp.CreateTime == 0 {
+		resp.CreateTime = time.Duration(time.Now().Unix())
+	}
+	data, err := authorizer.EncryptResponse(resp)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.Write(data)
+}
+
+//AppidFromPath 取出 /$APPID$/callback 形式路径中的appid
+//路径只有一段时返回该段
+func AppidFromPath(path string) string {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(parts) >= 2 {
+		return parts[len(parts)-2]
+	}
+	return parts[0]
+}
@@ -0,0 +1,136 @@
+package component
+
+import (
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wei193/component/wechat"
+)
+
+func TestMsgServer(t *testing.T) {
+	c := newTestComponent(t)
+	authorizer, _ := c.NewAuthorizer("wxauth", "token", 0, "refresh")
+	authorizer.UserName = "gh_1"
+	lookup := func(appid string) (*Authorizer, error) {
+		if appid != "wxauth" {
+			return nil, ErrAuthorizerNotFound
+		}
+		return authorizer, nil
+	}
+	s := c.NewMsgServer(lookup, func(a *Authorizer, req *wechat.STMsgRequest) (*wechat.STMsgResponse, error) {
+		return &wechat.STMsgResponse{MsgType: wechat.Text, Content: a.Appid + ":" + req.Content}, nil
+	})
+
+	encrypt, err := c.MsgEncrypt("<xml><ToUserName>gh_1</ToUserName><FromUserName>openid</FromUserName>" +
+		"<MsgType>text</MsgType><Content>hi</Content></xml>")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := "<xml><ToUserName>gh_1</ToUserName><Encrypt>" + encrypt + "</Encrypt></xml>"
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	q := url.Values{}
+	q.Set("timestamp", timestamp)
+	q.Set("nonce", "nonce")
+	q.Set("msg_signature", GetSignature(encrypt, c.ComponentToken, timestamp, "nonce"))
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest("POST", "/wxauth/callback?"+q.Encode(), strings.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	//重放同一回调应被拒绝
+	replay := httptest.NewRecorder()
+	s.ServeHTTP(replay, httptest.NewRequest("POST", "/wxauth/callback?"+q.Encode(), strings.NewReader(body)))
+	if replay.Code != http.StatusBadRequest {
+		t.Errorf("replayed callback status = %d, want 400", replay.Code)
+	}
+	var env XEncryptResponse
+	if err = xml.Unmarshal(rec.Body.Bytes(), &env); err != nil {
+		t.Fatal(err)
+	}
+	reply, err := c.DecryptRequest(env.MsgSignature.Value, strconv.FormatInt(env.TimeStamp, 10),
+		env.Nonce.Value, rec.Body.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply.Content != "wxauth:hi" || reply.ToUserName != "openid" || reply.FromUserName != "gh_1" {
+		t.Errorf("reply = %+v", reply)
+	}
+
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest("POST", "/wxother/callback?"+q.Encode(), strings.NewReader(body)))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("unknown appid status = %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	big := "<xml><Encrypt>" + strings.Repeat("a", maxEventBody) + "</Encrypt></xml>"
+	s.ServeHTTP(rec, httptest.NewRequest("POST", "/wxauth/callback?"+q.Encode(), strings.NewReader(big)))
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("oversized body status = %d", rec.Code)
+	}
+
+	//接收方不是该授权方的消息应被拒绝
+	authorizer.UserName = "gh_other"
+	sign := GetSignature(encrypt, c.ComponentToken, timestamp, "nonce2")
+	if _, err = authorizer.DecryptRequest(sign, timestamp, "nonce2", []byte(body)); err != ErrReceiverMismatch {
+		t.Errorf("DecryptRequest for other receiver err = %v", err)
+	}
+	authorizer.UserName = "gh_1"
+	sign = GetSignature(encrypt, c.ComponentToken, timestamp, "nonce3")
+	if _, err = authorizer.DecryptRequest(sign, timestamp, "nonce3", []byte(body)); err != nil {
+		t.Errorf("DecryptRequest for own receiver err = %v", err)
+	}
+}
+
+func TestMsgServerRetry(t *testing.T) {
+	c := newTestComponent(t)
+	authorizer, _ := c.NewAuthorizer("wxauth", "token", 0, "refresh")
+	authorizer.UserName = "gh_1"
+	fail := true
+	s := c.NewMsgServer(func(appid string) (*Authorizer, error) {
+		return authorizer, nil
+	}, func(a *Authorizer, req *wechat.STMsgRequest) (*wechat.STMsgResponse, error) {
+		if fail {
+			return nil, errors.New("handler failed")
+		}
+		return nil, nil
+	})
+
+	encrypt, err := c.MsgEncrypt("<xml><ToUserName>gh_1</ToUserName><FromUserName>openid</FromUserName>" +
+		"<MsgType>text</MsgType><Content>hi</Content></xml>")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := "<xml><ToUserName>gh_1</ToUserName><Encrypt>" + encrypt + "</Encrypt></xml>"
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	q := url.Values{}
+	q.Set("timestamp", timestamp)
+	q.Set("nonce", "retry")
+	q.Set("msg_signature", GetSignature(encrypt, c.ComponentToken, timestamp, "retry"))
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest("POST", "/wxauth/callback?"+q.Encode(), strings.NewReader(body)))
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("failed handler status = %d, want 500", rec.Code)
+	}
+	//处理失败后微信以相同参数重试，应再次处理
+	fail = false
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest("POST", "/wxauth/callback?"+q.Encode(), strings.NewReader(body)))
+	if rec.Code != http.StatusOK || rec.Body.String() != "success" {
+		t.Errorf("retry status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest("POST", "/wxauth/callback?"+q.Encode(), strings.NewReader(body)))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("replay after success status = %d, want 400", rec.Code)
+	}
+}
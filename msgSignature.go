@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"io"
 	"sort"
+
+	"github.com/wei193/component/common"
 )
 
 //GetSignature 获取签名信息
@@ -18,11 +20,30 @@ func GetSignature(msg, token, timestamp, nonce string) (signature string) {
 	return tmp
 }
 
-// CheckSignature  检查签名
+// CheckSignature  检查签名，不检查timestamp和nonce，处理回调时应使用VerifySignature
 func (c *Component) CheckSignature(signature, msg, timestamp, nonce string) bool {
 	msgSignature := GetSignature(msg, c.ComponentToken, timestamp, nonce)
-	if msgSignature == signature {
-		return true
+	return common.EqualSignature(signature, msgSignature)
+}
+
+//VerifySignature 校验回调签名、timestamp偏差及nonce是否重放
+func (c *Component) VerifySignature(signature, msg, timestamp, nonce string) error {
+	msgSignature := GetSignature(msg, c.ComponentToken, timestamp, nonce)
+	return c.verifier().Verify(signature, msgSignature, timestamp, nonce)
+}
+
+//ReleaseNonce 释放VerifySignature记录的nonce，回调处理失败需要微信重试时调用
+func (c *Component) ReleaseNonce(signature, timestamp, nonce string) error {
+	return c.verifier().Release(signature, timestamp, nonce)
+}
+
+//verifier 回调校验器，未设置Verifier时使用默认偏差和内存nonce缓存
+func (c *Component) verifier() *common.Verifier {
+	if c.Verifier != nil {
+		return c.Verifier
 	}
-	return false
+	c.verifierOnce.Do(func() {
+		c.defaultVerifier = common.NewVerifier()
+	})
+	return c.defaultVerifier
 }
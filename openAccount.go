@@ -0,0 +1,137 @@
+package component
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/wei193/component/common"
+)
+
+//开放平台帐号错误码
+const (
+	ErrcodeOpenAccountNotExist = 89002 //未绑定开放平台帐号
+	ErrcodeOpenAccountBound    = 89000 //已绑定开放平台帐号
+)
+
+//CreateOpenAccount 创建开放平台帐号并绑定授权方，返回开放平台帐号appid
+func (a *Authorizer) CreateOpenAccount() (openAppid string, err error) {
+	return a.CreateOpenAccountContext(context.Background())
+}
+
+//CreateOpenAccountContext 同CreateOpenAccount，使用ctx控制请求的取消和超时
+func (a *Authorizer) CreateOpenAccountContext(ctx context.Context) (openAppid string, err error) {
+	return a.requsetOpen(ctx, "https://api.weixin.qq.com/cgi-bin/open/create", "")
+}
+
+//BindOpenAccount 将授权方绑定到开放平台帐号
+func (a *Authorizer) BindOpenAccount(openAppid string) error {
+	return a.BindOpenAccountContext(context.Background(), openAppid)
+}
+
+//BindOpenAccountContext 同BindOpenAccount，使用ctx控制请求的取消和超时
+func (a *Authorizer) BindOpenAccountContext(ctx context.Context, openAppid string) error {
+	_, err := a.requsetOpen(ctx, "https://api.weixin.qq.com/cgi-bin/open/bind", openAppid)
+	return err
+}
+
+//UnbindOpenAccount 将授权方从开放平台帐号解绑
+func (a *Authorizer) UnbindOpenAccount(openAppid string) error {
+	return a.UnbindOpenAccountContext(context.Background(), openAppid)
+}
+
+//UnbindOpenAccountContext 同UnbindOpenAccount，使用ctx控制请求的取消和超时
+func (a *Authorizer) UnbindOpenAccountContext(ctx context.Context, openAppid string) error {
+	_, err := a.requsetOpen(ctx, "https://api.weixin.qq.com/cgi-bin/open/unbind", openAppid)
+	return err
+}
+
+//GetOpenAccount 获取授权方绑定的开放平台帐号appid，未绑定时返回空
+func (a *Authorizer) GetOpenAccount() (openAppid string, err error) {
+	return a.GetOpenAccountContext(context.Background())
+}
+
+//GetOpenAccountContext 同GetOpenAccount，使用ctx控制请求的取消和超时
+func (a *Authorizer) GetOpenAccountContext(ctx context.Context) (openAppid string, err error) {
+	openAppid, err = a.requsetOpen(ctx, "https://api.weixin.qq.com/cgi-bin/open/get", "")
+	if e, ok := common.AsAPIError(err); ok && e.Errcode == ErrcodeOpenAccountNotExist {
+		return "", nil
+	}
+	return openAppid, err
+}
+
+//requsetOpen 发送开放平台帐号管理请求，返回open_appid
+func (a *Authorizer) requsetOpen(ctx context.Context, surl, openAppid string) (string, error) {
+	type st struct {
+		Appid     string `json:"appid"`
+		OpenAppid string `json:"open_appid,omitempty"`
+	}
+	res, err := a.requsetWithToken(ctx, surl, "POST", nil, st{a.Appid, openAppid})
+	if err != nil {
+		return "", err
+	}
+	var data struct {
+		OpenAppid string `json:"open_appid"`
+	}
+	err = json.Unmarshal(res, &data)
+	if err != nil {
+		return "", err
+	}
+	return data.OpenAppid, nil
+}
+
+//OpenAccountConflictError 授权方已绑定到不同的开放平台帐号
+type OpenAccountConflictError struct {
+	//Accounts 授权方appid对应的开放平台帐号appid
+	Accounts map[string]string
+}
+
+func (e *OpenAccountConflictError) Error() string {
+	return fmt.Sprintf("authorizers are bound to different open accounts: %v", e.Accounts)
+}
+
+//EnsureSameOpenAccount 确保授权方绑定到同一个开放平台帐号，返回开放平台帐号appid
+//均未绑定时使用第一个授权方创建，已绑定到不同帐号时返回*OpenAccountConflictError且不做修改
+func (c *Component) EnsureSameOpenAccount(authorizers []*Authorizer) (openAppid string, err error) {
+	return c.EnsureSameOpenAccountContext(context.Background(), authorizers)
+}
+
+//EnsureSameOpenAccountContext 同EnsureSameOpenAccount，使用ctx控制请求的取消和超时
+func (c *Component) EnsureSameOpenAccountContext(ctx context.Context, authorizers []*Authorizer) (openAppid string, err error) {
+	accounts := make(map[string]string)
+	var unbound []*Authorizer
+	for _, a := range authorizers {
+		id, err := a.GetOpenAccountContext(ctx)
+		if err != nil {
+			return "", err
+		}
+		if id == "" {
+			unbound = append(unbound, a)
+			continue
+		}
+		accounts[a.Appid] = id
+		if openAppid == "" {
+			openAppid = id
+		}
+	}
+	for _, id := range accounts {
+		if id != openAppid {
+			return "", &OpenAccountConflictError{Accounts: accounts}
+		}
+	}
+
+	if openAppid == "" && len(unbound) > 0 {
+		openAppid, err = unbound[0].CreateOpenAccountContext(ctx)
+		if err != nil {
+			return "", err
+		}
+		unbound = unbound[1:]
+	}
+	for _, a := range unbound {
+		err = a.BindOpenAccountContext(ctx, openAppid)
+		if err != nil {
+			return openAppid, err
+		}
+	}
+	return openAppid, nil
+}
@@ -0,0 +1,101 @@
+package component_test
+
+import (
+	"testing"
+
+	"github.com/wei193/component"
+	"github.com/wei193/component/wechattest"
+)
+
+func TestEnsureSameOpenAccount(t *testing.T) {
+	srv := wechattest.NewServer()
+	defer srv.Close()
+	c, err := srv.Component(wechattest.ComponentAppid, wechattest.Appsecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newAuthorizers := func(appids ...string) []*component.Authorizer {
+		var list []*component.Authorizer
+		for _, appid := range appids {
+			a, err := c.NewAuthorizer(appid, srv.IssueToken(), 1<<62, "REFRESH_"+appid)
+			if err != nil {
+				t.Fatal(err)
+			}
+			list = append(list, a)
+		}
+		return list
+	}
+
+	//均未绑定时创建并绑定
+	list := newAuthorizers("wxmp", "wxmini")
+	openAppid, err := c.EnsureSameOpenAccount(list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if openAppid == "" || srv.OpenAccount("wxmp") != openAppid || srv.OpenAccount("wxmini") != openAppid {
+		t.Errorf("open accounts = %s %s, want %s", srv.OpenAccount("wxmp"), srv.OpenAccount("wxmini"), openAppid)
+	}
+	if n := srv.Calls("/cgi-bin/open/create"); n != 1 {
+		t.Errorf("create calls = %d, want 1", n)
+	}
+
+	//已有帐号时绑定其余授权方
+	srv.BindOpenAccount("wxbound", "wxopen_exist")
+	list = newAuthorizers("wxnew", "wxbound")
+	if openAppid, err = c.EnsureSameOpenAccount(list); err != nil || openAppid != "wxopen_exist" {
+		t.Fatalf("EnsureSameOpenAccount = %s, %v", openAppid, err)
+	}
+	if srv.OpenAccount("wxnew") != "wxopen_exist" {
+		t.Errorf("wxnew bound to %s", srv.OpenAccount("wxnew"))
+	}
+
+	//绑定到不同帐号时不做修改
+	list = newAuthorizers("wxmp", "wxbound", "wxother")
+	_, err = c.EnsureSameOpenAccount(list)
+	if _, ok := err.(*component.OpenAccountConflictError); !ok {
+		t.Fatalf("err = %v, want OpenAccountConflictError", err)
+	}
+	if srv.OpenAccount("wxother") != "" {
+		t.Error("wxother bound despite conflict")
+	}
+
+	if err = list[1].UnbindOpenAccount("wxopen_exist"); err != nil {
+		t.Fatal(err)
+	}
+	if id, err := list[1].GetOpenAccount(); err != nil || id != "" {
+		t.Errorf("GetOpenAccount after unbind = %q, %v", id, err)
+	}
+}
+
+func TestEnsureSameOpenAccountScopeGuard(t *testing.T) {
+	srv := wechattest.NewServer()
+	defer srv.Close()
+	c, err := srv.Component(wechattest.ComponentAppid, wechattest.Appsecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	//公众号授予24，小程序授予25
+	scopes := map[string]component.Scope{"wxmp": component.ScopeOpenAccount, "wxmini": component.ScopeWxaOpenAccount}
+	var list []*component.Authorizer
+	for _, appid := range []string{"wxmp", "wxmini"} {
+		a, err := c.NewAuthorizer(appid, srv.IssueToken(), 1<<62, "REFRESH_"+appid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		a.Scopes = component.ScopeSet{scopes[appid]: true}
+		a.EnableScopeGuard()
+		list = append(list, a)
+	}
+	openAppid, err := c.EnsureSameOpenAccount(list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if srv.OpenAccount("wxmini") != openAppid {
+		t.Errorf("wxmini bound to %s, want %s", srv.OpenAccount("wxmini"), openAppid)
+	}
+
+	list[1].Scopes = component.ScopeSet{component.ScopeWxaCode: true}
+	if _, err = list[1].GetOpenAccount(); err == nil {
+		t.Error("GetOpenAccount without open account scope succeeded")
+	}
+}
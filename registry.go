@@ -0,0 +1,216 @@
+package component
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+//Registry 授权方注册表
+//通过AuthorizerStore加载和保存授权方，后台在令牌过期前批量刷新
+type Registry struct {
+	Component *Component
+	Store     AuthorizerStore
+	//Concurrency 同时刷新的授权方数量
+	Concurrency int
+	//Ahead 距离过期不足该时间的令牌会被刷新
+	Ahead time.Duration
+	//Interval 后台检查间隔
+	Interval time.Duration
+	//OnRefreshError 刷新失败回调
+	OnRefreshError func(appid string, err error)
+
+	lock        sync.RWMutex
+	authorizers map[string]*Authorizer
+}
+
+//NewRegistry 新建授权方注册表
+func (c *Component) NewRegistry(store AuthorizerStore) *Registry {
+	return &Registry{
+		Component:   c,
+		Store:       store,
+		Concurrency: 10,
+		Ahead:       15 * time.Minute,
+		Interval:    time.Minute,
+		authorizers: make(map[string]*Authorizer),
+	}
+}
+
+//Load 从存储加载全部授权方
+func (r *Registry) Load() error {
+	records, err := r.Store.LoadAuthorizers()
+	if err != nil {
+		return err
+	}
+	authorizers := make(map[string]*Authorizer, len(records))
+	for _, record := range records {
+		a, err := r.Component.NewAuthorizer(record.Appid, record.AccessToken,
+			record.AccessTokenExpires, record.AuthorizerRefreshToken)
+		if err != nil {
+			return err
+		}
+		if record.Scopes != nil {
+			a.Scopes = make(ScopeSet, len(record.Scopes))
+			for _, scope := range record.Scopes {
+				a.Scopes[scope] = true
+			}
+		}
+		a.UserName = record.UserName
+		authorizers[record.Appid] = a
+	}
+	r.lock.Lock()
+	r.authorizers = authorizers
+	r.lock.Unlock()
+	return nil
+}
+
+//Add 添加授权方并保存
+func (r *Registry) Add(a *Authorizer) error {
+	err := r.Store.SaveAuthorizer(a.record())
+	if err != nil {
+		return err
+	}
+	r.lock.Lock()
+	r.authorizers[a.Appid] = a
+	r.lock.Unlock()
+	return nil
+}
+
+//Remove 删除授权方
+func (r *Registry) Remove(appid string) error {
+	err := r.Store.DeleteAuthorizer(appid)
+	if err != nil {
+		return err
+	}
+	r.lock.Lock()
+	delete(r.authorizers, appid)
+	r.lock.Unlock()
+	return nil
+}
+
+//Get 按appid查找授权方，可作为MsgServer的AuthorizerLookup
+func (r *Registry) Get(appid string) (*Authorizer, error) {
+	r.lock.RLock()
+	a, ok := r.authorizers[appid]
+	r.lock.RUnlock()
+	if !ok {
+		return nil, ErrAuthorizerNotFound
+	}
+	return a, nil
+}
+
+//List 全部授权方，按appid排序
+func (r *Registry) List() []*Authorizer {
+	r.lock.RLock()
+	list := make([]*Authorizer, 0, len(r.authorizers))
+	for _, a := range r.authorizers {
+		list = append(list, a)
+	}
+	r.lock.RUnlock()
+	sort.Slice(list, func(i, j int) bool { return list[i].Appid < list[j].Appid })
+	return list
+}
+
+//HandleAuthorized 授权成功或更新授权事件，使用授权码换取令牌后加入注册表
+//可作为AuthEventHandler的OnAuthorized及OnUpdateAuthorized
+func (r *Registry) HandleAuthorized(event *XCEvent) error {
+	a, err := r.Component.QueryAuth(event.AuthorizationCode)
+	if err != nil {
+		return err
+	}
+	return r.Add(a)
+}
+
+//HandleUnauthorized 取消授权事件，从注册表中删除，可作为AuthEventHandler的OnUnauthorized
+func (r *Registry) HandleUnauthorized(event *XCEvent) error {
+	return r.Remove(event.AuthorizerAppid)
+}
+
+//Refresh 刷新即将过期的授权方令牌，返回刷新失败的授权方及错误
+func (r *Registry) Refresh() map[string]error {
+	return r.RefreshContext(context.Background())
+}
+
+//RefreshContext 同Refresh，使用ctx控制请求的取消和超时
+func (r *Registry) RefreshContext(ctx context.Context) map[string]error {
+	deadline := time.Now().Add(r.Ahead).Unix()
+	var expiring []*Authorizer
+	for _, a := range r.List() {
+		if _, expires := a.CurrentAccessToken(); expires <= deadline {
+			expiring = append(expiring, a)
+		}
+	}
+
+	concurrency := r.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	var (
+		wg   sync.WaitGroup
+		lock sync.Mutex
+		errs = make(map[string]error)
+		sem  = make(chan struct{}, concurrency)
+	)
+	for _, a := range expiring {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(a *Authorizer) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			_, err := a.GetAuthorizerAccessTokenContext(ctx)
+			if err == nil {
+				err = r.Store.SaveAuthorizer(a.record())
+			}
+			if err != nil {
+				lock.Lock()
+				errs[a.Appid] = err
+				lock.Unlock()
+				if r.OnRefreshError != nil {
+					r.OnRefreshError(a.Appid, err)
+				}
+			}
+		}(a)
+	}
+	wg.Wait()
+	return errs
+}
+
+//Run 后台定时刷新，直到ctx结束
+func (r *Registry) Run(ctx context.Context) {
+	interval := r.Interval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		r.RefreshContext(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+//record 授权方持久化信息
+func (a *Authorizer) record() AuthorizerRecord {
+	token, expires := a.CurrentAccessToken()
+	a.infoLock.Lock()
+	userName := a.UserName
+	a.infoLock.Unlock()
+	record := AuthorizerRecord{
+		Appid:                  a.Appid,
+		AuthorizerRefreshToken: a.RefreshToken(),
+		AccessToken:            token,
+		AccessTokenExpires:     expires,
+		UserName:               userName,
+	}
+	if a.Scopes != nil {
+		record.Scopes = a.Scopes.List()
+	}
+	return record
+}
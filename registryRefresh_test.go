@@ -0,0 +1,125 @@
+package component_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/wei193/component"
+	"github.com/wei193/component/common"
+	"github.com/wei193/component/wechattest"
+)
+
+func TestRegistryRefresh(t *testing.T) {
+	srv := wechattest.NewServer()
+	defer srv.Close()
+	c, err := srv.Component(wechattest.ComponentAppid, wechattest.Appsecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//模拟较慢的刷新接口，记录同时处理的请求数，并轮换authorizer_refresh_token
+	var (
+		lock           sync.Mutex
+		inflight, peak int
+		refreshed      = make(map[string]string)
+	)
+	srv.Handle("/cgi-bin/component/api_authorizer_token", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			AuthorizerAppid        string `json:"authorizer_appid"`
+			AuthorizerRefreshToken string `json:"authorizer_refresh_token"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		lock.Lock()
+		inflight++
+		if inflight > peak {
+			peak = inflight
+		}
+		refreshed[req.AuthorizerAppid] = req.AuthorizerRefreshToken
+		lock.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		lock.Lock()
+		inflight--
+		lock.Unlock()
+		if req.AuthorizerAppid == "wxfail" {
+			w.Write([]byte(`{"errcode":61023,"errmsg":"refresh_token is invalid"}`))
+			return
+		}
+		json.NewEncoder(w).Encode(component.JAuthorizerAccessToken{
+			AuthorizerAccessToken:  srv.IssueToken(),
+			ExpiresIn:              7200,
+			AuthorizerRefreshToken: "ROTATED_" + req.AuthorizerAppid,
+		})
+	})
+
+	store := component.NewMemoryAuthorizerStore()
+	r := c.NewRegistry(store)
+	r.Concurrency = 2
+	var reported []string
+	r.OnRefreshError = func(appid string, err error) {
+		lock.Lock()
+		reported = append(reported, appid)
+		lock.Unlock()
+	}
+	expiring := []string{"wxfail"}
+	for i := 0; i < 5; i++ {
+		expiring = append(expiring, fmt.Sprintf("wxexpiring%d", i))
+	}
+	for _, appid := range expiring {
+		a, err := c.NewAuthorizer(appid, "", 0, "REFRESH_"+appid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = r.Add(a); err != nil {
+			t.Fatal(err)
+		}
+	}
+	fresh, err := c.NewAuthorizer("wxfresh", srv.IssueToken(), time.Now().Unix()+7200, "REFRESH_wxfresh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = r.Add(fresh); err != nil {
+		t.Fatal(err)
+	}
+
+	errs := r.Refresh()
+	if len(errs) != 1 {
+		t.Errorf("errs = %v", errs)
+	}
+	if e, ok := common.AsAPIError(errs["wxfail"]); !ok || e.Errcode != 61023 {
+		t.Errorf("wxfail err = %v", errs["wxfail"])
+	}
+	if len(reported) != 1 || reported[0] != "wxfail" {
+		t.Errorf("OnRefreshError reported %v", reported)
+	}
+	//只刷新即将过期的授权方，同时进行的刷新不超过Concurrency
+	if _, ok := refreshed["wxfresh"]; ok || len(refreshed) != len(expiring) {
+		t.Errorf("refreshed = %v", refreshed)
+	}
+	if peak > r.Concurrency || peak < 2 {
+		t.Errorf("peak concurrent refreshes = %d, want %d", peak, r.Concurrency)
+	}
+
+	records, err := store.LoadAuthorizers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, record := range records {
+		want := "ROTATED_" + record.Appid
+		if record.Appid == "wxfail" || record.Appid == "wxfresh" {
+			want = "REFRESH_" + record.Appid
+		}
+		if record.AuthorizerRefreshToken != want {
+			t.Errorf("%s refresh token = %s, want %s", record.Appid, record.AuthorizerRefreshToken, want)
+		}
+		if record.Appid != "wxfail" && !common.TokenValid(record.AccessToken, record.AccessTokenExpires) {
+			t.Errorf("%s access token not saved: %+v", record.Appid, record)
+		}
+	}
+	if len(records) != len(expiring)+1 {
+		t.Errorf("records = %+v", records)
+	}
+}
@@ -0,0 +1,44 @@
+package component
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRegistry(t *testing.T) {
+	dir, err := ioutil.TempDir("", "registry")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	store := NewFileAuthorizerStore(filepath.Join(dir, "authorizers.json"))
+
+	c := newTestComponent(t)
+	r := c.NewRegistry(store)
+	for _, appid := range []string{"wx2", "wx1"} {
+		a, _ := c.NewAuthorizer(appid, "token-"+appid, 0, "refresh-"+appid)
+		if err = r.Add(a); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = r.HandleUnauthorized(&XCEvent{InfoType: InfoTypeUnauthorized, AuthorizerAppid: "wx2"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = r.Get("wx2"); err != ErrAuthorizerNotFound {
+		t.Errorf("Get removed authorizer: %v", err)
+	}
+
+	loaded := c.NewRegistry(store)
+	if err = loaded.Load(); err != nil {
+		t.Fatal(err)
+	}
+	list := loaded.List()
+	if len(list) != 1 || list[0].Appid != "wx1" || list[0].AuthorizerRefreshToken != "refresh-wx1" {
+		t.Fatalf("loaded = %+v", list)
+	}
+	if list[0].TokenFunc == nil {
+		t.Error("loaded authorizer has no TokenFunc")
+	}
+}
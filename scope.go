@@ -0,0 +1,189 @@
+package component
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//Scope 授权给第三方平台的权限集，即funcscope_category的id
+type Scope int
+
+//公众号权限集
+const (
+	ScopeMessage         Scope = 1  //消息管理
+	ScopeUser            Scope = 2  //用户管理
+	ScopeAccount         Scope = 3  //帐号服务
+	ScopeWeb             Scope = 4  //网页服务
+	ScopeShop            Scope = 5  //微信小店
+	ScopeCustomerService Scope = 6  //微信多客服
+	ScopeMassSend        Scope = 7  //群发与通知
+	ScopeCard            Scope = 8  //微信卡券
+	ScopeScan            Scope = 9  //微信扫一扫
+	ScopeWifi            Scope = 10 //微信连WIFI
+	ScopeMaterial        Scope = 11 //素材管理
+	ScopeShake           Scope = 12 //微信摇周边
+	ScopePoi             Scope = 13 //微信门店
+	ScopeMenu            Scope = 15 //自定义菜单
+	ScopeCityService     Scope = 22 //城市服务接口
+	ScopeAd              Scope = 23 //广告管理
+	ScopeOpenAccount     Scope = 24 //开放平台帐号管理
+	ScopeInvoice         Scope = 26 //微信电子发票
+	ScopeFastRegister    Scope = 27 //快速注册小程序
+	ScopeMiniProgram     Scope = 33 //小程序管理
+)
+
+//小程序权限集
+const (
+	ScopeWxaAccount         Scope = 17 //帐号管理
+	ScopeWxaCode            Scope = 18 //开发管理与数据分析
+	ScopeWxaCustomerService Scope = 19 //客服消息管理
+	ScopeWxaOpenAccount     Scope = 25 //开放平台帐号管理
+	ScopeWxaBasicInfo       Scope = 30 //小程序基本信息设置
+	ScopeWxaVerify          Scope = 31 //小程序认证
+)
+
+var scopeNames = map[Scope]string{
+	ScopeMessage:            "消息管理权限",
+	ScopeUser:               "用户管理权限",
+	ScopeAccount:            "帐号服务权限",
+	ScopeWeb:                "网页服务权限",
+	ScopeShop:               "微信小店权限",
+	ScopeCustomerService:    "微信多客服权限",
+	ScopeMassSend:           "群发与通知权限",
+	ScopeCard:               "微信卡券权限",
+	ScopeScan:               "微信扫一扫权限",
+	ScopeWifi:               "微信连WIFI权限",
+	ScopeMaterial:           "素材管理权限",
+	ScopeShake:              "微信摇周边权限",
+	ScopePoi:                "微信门店权限",
+	ScopeMenu:               "自定义菜单权限",
+	ScopeCityService:        "城市服务接口权限",
+	ScopeAd:                 "广告管理权限",
+	ScopeOpenAccount:        "开放平台帐号管理权限",
+	ScopeInvoice:            "微信电子发票权限",
+	ScopeFastRegister:       "快速注册小程序权限",
+	ScopeMiniProgram:        "小程序管理权限",
+	ScopeWxaAccount:         "小程序帐号管理权限",
+	ScopeWxaCode:            "小程序开发管理与数据分析权限",
+	ScopeWxaCustomerService: "小程序客服消息管理权限",
+	ScopeWxaOpenAccount:     "小程序开放平台帐号管理权限",
+	ScopeWxaBasicInfo:       "小程序基本信息设置权限",
+	ScopeWxaVerify:          "小程序认证权限",
+}
+
+//String 权限集名称
+func (s Scope) String() string {
+	if name, ok := scopeNames[s]; ok {
+		return name + "(" + strconv.Itoa(int(s)) + ")"
+	}
+	return "funcscope_category(" + strconv.Itoa(int(s)) + ")"
+}
+
+//ScopeSet 权限集合
+type ScopeSet map[Scope]bool
+
+//NewScopeSet 由授权信息中的func_info生成权限集合
+func NewScopeSet(funcInfo []JFuncInfo) ScopeSet {
+	set := make(ScopeSet, len(funcInfo))
+	for _, f := range funcInfo {
+		set[Scope(f.FuncscopeCategory.ID)] = true
+	}
+	return set
+}
+
+//Has 是否包含权限集
+func (s ScopeSet) Has(scope Scope) bool {
+	return s[scope]
+}
+
+//List 权限集列表，按id排序
+func (s ScopeSet) List() []Scope {
+	list := make([]Scope, 0, len(s))
+	for scope, ok := range s {
+		if ok {
+			list = append(list, scope)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i] < list[j] })
+	return list
+}
+
+//ScopeError 授权方未授予接口所需的权限集
+type ScopeError struct {
+	Appid string
+	Scope Scope
+	API   string
+}
+
+func (e *ScopeError) Error() string {
+	return fmt.Sprintf("authorizer %s has not granted %s required by %s", e.Appid, e.Scope, e.API)
+}
+
+//apiScopes 接口路径前缀所需的权限集，授予其中任一权限集即可调用
+var apiScopes = []struct {
+	prefix string
+	scopes []Scope
+}{
+	{"/cgi-bin/menu/", []Scope{ScopeMenu}},
+	{"/cgi-bin/user/", []Scope{ScopeUser}},
+	{"/cgi-bin/tags/", []Scope{ScopeUser}},
+	{"/cgi-bin/material/", []Scope{ScopeMaterial}},
+	{"/cgi-bin/media/", []Scope{ScopeMaterial}},
+	{"/cgi-bin/message/mass/", []Scope{ScopeMassSend}},
+	{"/cgi-bin/message/template/", []Scope{ScopeMassSend}},
+	//客服消息接口属于消息管理权限集，也可由多客服权限集调用
+	{"/cgi-bin/message/custom/", []Scope{ScopeMessage, ScopeCustomerService}},
+	{"/customservice/", []Scope{ScopeCustomerService}},
+	{"/card/", []Scope{ScopeCard}},
+	{"/cgi-bin/poi/", []Scope{ScopePoi}},
+	//公众号与小程序的开放平台帐号管理权限集id不同
+	{"/cgi-bin/open/", []Scope{ScopeOpenAccount, ScopeWxaOpenAccount}},
+	{"/wxa/", []Scope{ScopeWxaCode}},
+}
+
+//APIScope 接口所需的权限集，授予其中任一权限集即可调用，不需要检查时返回false
+func APIScope(path string) ([]Scope, bool) {
+	for _, s := range apiScopes {
+		if strings.HasPrefix(path, s.prefix) {
+			return s.scopes, true
+		}
+	}
+	return nil, false
+}
+
+//HasScope 授权方是否授予了权限集，未获取授权信息时返回true
+func (a *Authorizer) HasScope(scope Scope) bool {
+	if a.Scopes == nil {
+		return true
+	}
+	return a.Scopes.Has(scope)
+}
+
+//CheckScope 检查授权方是否授予了调用接口所需的权限集
+func (a *Authorizer) CheckScope(scope Scope, api string) error {
+	if a.HasScope(scope) {
+		return nil
+	}
+	return &ScopeError{Appid: a.Appid, Scope: scope, API: api}
+}
+
+//EnableScopeGuard 开启调用前的权限检查，未授予所需权限集时直接返回ScopeError而不请求微信
+func (a *Authorizer) EnableScopeGuard() {
+	a.Guard = a.scopeGuard
+}
+
+func (a *Authorizer) scopeGuard(req *http.Request) error {
+	scopes, ok := APIScope(req.URL.Path)
+	if !ok {
+		return nil
+	}
+	for _, scope := range scopes {
+		if a.HasScope(scope) {
+			return nil
+		}
+	}
+	return a.CheckScope(scopes[0], req.URL.Path)
+}
@@ -0,0 +1,53 @@
+package component
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestScopeGuard(t *testing.T) {
+	c := newTestComponent(t)
+	a, _ := c.NewAuthorizer("wxauth", "token", 0, "refresh")
+	a.Scopes = NewScopeSet([]JFuncInfo{
+		{FuncscopeCategory: JFuncscopeCategory{ID: int(ScopeMessage)}},
+		{FuncscopeCategory: JFuncscopeCategory{ID: int(ScopeUser)}},
+	})
+	if !a.HasScope(ScopeUser) || a.HasScope(ScopeMenu) {
+		t.Fatalf("HasScope wrong for %v", a.Scopes.List())
+	}
+
+	a.EnableScopeGuard()
+	_, err := a.GetMenu()
+	serr, ok := err.(*ScopeError)
+	if !ok {
+		t.Fatalf("GetMenu err = %v, want *ScopeError", err)
+	}
+	if serr.Scope != ScopeMenu || serr.API != "/cgi-bin/menu/get" {
+		t.Errorf("ScopeError = %+v", serr)
+	}
+
+	//公众号与小程序的开放平台帐号管理权限集id不同，授予其一即可
+	req, _ := http.NewRequest("POST", "https://api.weixin.qq.com/cgi-bin/open/get", nil)
+	for _, scope := range []Scope{ScopeOpenAccount, ScopeWxaOpenAccount} {
+		a.Scopes = NewScopeSet([]JFuncInfo{{FuncscopeCategory: JFuncscopeCategory{ID: int(scope)}}})
+		if err = a.scopeGuard(req); err != nil {
+			t.Errorf("scopeGuard with %s = %v", scope, err)
+		}
+	}
+	a.Scopes = NewScopeSet(nil)
+	if serr, ok := a.scopeGuard(req).(*ScopeError); !ok || serr.Scope != ScopeOpenAccount {
+		t.Errorf("scopeGuard without open account scope = %v", serr)
+	}
+
+	req, _ = http.NewRequest("POST", "https://api.weixin.qq.com/cgi-bin/message/custom/send", nil)
+	for _, scope := range []Scope{ScopeMessage, ScopeCustomerService} {
+		a.Scopes = NewScopeSet([]JFuncInfo{{FuncscopeCategory: JFuncscopeCategory{ID: int(scope)}}})
+		if err = a.scopeGuard(req); err != nil {
+			t.Errorf("custom message scopeGuard with %s = %v", scope, err)
+		}
+	}
+	a.Scopes = NewScopeSet([]JFuncInfo{{FuncscopeCategory: JFuncscopeCategory{ID: int(ScopeUser)}}})
+	if _, ok := a.scopeGuard(req).(*ScopeError); !ok {
+		t.Error("custom message scopeGuard without message scope passed")
+	}
+}
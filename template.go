@@ -0,0 +1,219 @@
+package component
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"strconv"
+	"sync"
+
+	"github.com/wei193/component/common"
+)
+
+//TemplateType 代码模板类型
+type TemplateType int
+
+//代码模板类型
+const (
+	TemplateNormal   TemplateType = 0 //普通模板
+	TemplateStandard TemplateType = 1 //标准模板
+	//TemplateAll 查询模板列表时表示全部类型
+	TemplateAll TemplateType = -1
+)
+
+//JTemplateDraft 代码草稿
+type JTemplateDraft struct {
+	DraftID                int64  `json:"draft_id"`
+	CreateTime             int64  `json:"create_time"`
+	UserVersion            string `json:"user_version"`
+	UserDesc               string `json:"user_desc"`
+	SourceMiniprogramAppid string `json:"source_miniprogram_appid"`
+	SourceMiniprogram      string `json:"source_miniprogram"`
+	Developer              string `json:"developer"`
+}
+
+//JTemplate 代码模板
+type JTemplate struct {
+	TemplateID             int64        `json:"template_id"`
+	DraftID                int64        `json:"draft_id"`
+	TemplateType           TemplateType `json:"template_type"`
+	CreateTime             int64        `json:"create_time"`
+	UserVersion            string       `json:"user_version"`
+	UserDesc               string       `json:"user_desc"`
+	SourceMiniprogramAppid string       `json:"source_miniprogram_appid"`
+	SourceMiniprogram      string       `json:"source_miniprogram"`
+	Developer              string       `json:"developer"`
+}
+
+//requsetTemplate 发送代码模板库请求
+//模板库接口使用access_token参数传递component_access_token，令牌无效时刷新并重放
+func (c *Component) requsetTemplate(ctx context.Context, surl, method string, p map[string]string, d interface{}) ([]byte, error) {
+	accessToken, err := c.TokenContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	req, err := createRequset(ctx, surl+"?access_token="+url.QueryEscape(accessToken), method, p, d)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(common.WithLogFields(req.Context(), common.F("component_appid", c.ComponentAppid)))
+	return c.APIClient().RequsetJSON(req, "access_token", c.refreshToken)
+}
+
+//GetTemplateDraftList 获取代码草稿列表
+func (c *Component) GetTemplateDraftList() ([]JTemplateDraft, error) {
+	return c.GetTemplateDraftListContext(context.Background())
+}
+
+//GetTemplateDraftListContext 同GetTemplateDraftList，使用ctx控制请求的取消和超时
+func (c *Component) GetTemplateDraftListContext(ctx context.Context) ([]JTemplateDraft, error) {
+	res, err := c.requsetTemplate(ctx, "https://api.weixin.qq.com/wxa/gettemplatedraftlist", "GET", nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	var data struct {
+		DraftList []JTemplateDraft `json:"draft_list"`
+	}
+	err = json.Unmarshal(res, &data)
+	if err != nil {
+		return nil, err
+	}
+	return data.DraftList, nil
+}
+
+//AddToTemplate 将草稿添加到代码模板库
+func (c *Component) AddToTemplate(draftID int64, templateType TemplateType) error {
+	return c.AddToTemplateContext(context.Background(), draftID, templateType)
+}
+
+//AddToTemplateContext 同AddToTemplate，使用ctx控制请求的取消和超时
+func (c *Component) AddToTemplateContext(ctx context.Context, draftID int64, templateType TemplateType) error {
+	type st struct {
+		DraftID      int64        `json:"draft_id"`
+		TemplateType TemplateType `json:"template_type"`
+	}
+	_, err := c.requsetTemplate(ctx, "https://api.weixin.qq.com/wxa/addtotemplate", "POST", nil, st{draftID, templateType})
+	return err
+}
+
+//GetTemplateList 获取代码模板列表，templateType为TemplateAll时返回全部类型
+func (c *Component) GetTemplateList(templateType TemplateType) ([]JTemplate, error) {
+	return c.GetTemplateListContext(context.Background(), templateType)
+}
+
+//GetTemplateListContext 同GetTemplateList，使用ctx控制请求的取消和超时
+func (c *Component) GetTemplateListContext(ctx context.Context, templateType TemplateType) ([]JTemplate, error) {
+	param := make(map[string]string)
+	if templateType >= 0 {
+		param["template_type"] = strconv.Itoa(int(templateType))
+	}
+	res, err := c.requsetTemplate(ctx, "https://api.weixin.qq.com/wxa/gettemplatelist", "GET", param, nil)
+	if err != nil {
+		return nil, err
+	}
+	var data struct {
+		TemplateList []JTemplate `json:"template_list"`
+	}
+	err = json.Unmarshal(res, &data)
+	if err != nil {
+		return nil, err
+	}
+	return data.TemplateList, nil
+}
+
+//DeleteTemplate 删除代码模板
+func (c *Component) DeleteTemplate(templateID int64) error {
+	return c.DeleteTemplateContext(context.Background(), templateID)
+}
+
+//DeleteTemplateContext 同DeleteTemplate，使用ctx控制请求的取消和超时
+func (c *Component) DeleteTemplateContext(ctx context.Context, templateID int64) error {
+	type st struct {
+		TemplateID int64 `json:"template_id"`
+	}
+	_, err := c.requsetTemplate(ctx, "https://api.weixin.qq.com/wxa/deletetemplate", "POST", nil, st{templateID})
+	return err
+}
+
+//DefaultUpgradeConcurrency 批量升级默认同时处理的授权方数量
+const DefaultUpgradeConcurrency = 5
+
+//TemplateUpgrade 批量将代码模板上传到授权的小程序
+type TemplateUpgrade struct {
+	//Commit 上传的代码，TemplateID为代码模板ID
+	Commit JWxaCommit
+	//ExtJSON 按授权方生成ext_json，为空时使用Commit.ExtJSON
+	ExtJSON func(a *Authorizer) (string, error)
+	//Audit 上传后提交审核，为空时只上传代码
+	Audit *JWxaAudit
+	//Concurrency 同时处理的授权方数量，小于等于0时为DefaultUpgradeConcurrency
+	Concurrency int
+	//OnResult 每个授权方处理完成后调用，会被并发调用
+	OnResult func(result UpgradeResult)
+}
+
+//UpgradeResult 单个授权方的升级结果
+type UpgradeResult struct {
+	Appid string
+	//Auditid 提交审核时的审核编号
+	Auditid int64
+	Err     error
+}
+
+//Upgrade 将代码模板上传到全部授权方，返回与authorizers顺序一致的结果
+func (c *Component) Upgrade(authorizers []*Authorizer, u TemplateUpgrade) []UpgradeResult {
+	return c.UpgradeContext(context.Background(), authorizers, u)
+}
+
+//UpgradeContext 同Upgrade，ctx结束后未开始的授权方返回ctx.Err()
+func (c *Component) UpgradeContext(ctx context.Context, authorizers []*Authorizer, u TemplateUpgrade) []UpgradeResult {
+	concurrency := u.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultUpgradeConcurrency
+	}
+	var (
+		wg      sync.WaitGroup
+		sem     = make(chan struct{}, concurrency)
+		results = make([]UpgradeResult, len(authorizers))
+	)
+	for i, a := range authorizers {
+		results[i].Appid = a.Appid
+		select {
+		case <-ctx.Done():
+			results[i].Err = ctx.Err()
+			if u.OnResult != nil {
+				u.OnResult(results[i])
+			}
+			continue
+		case sem <- struct{}{}:
+		}
+		wg.Add(1)
+		go func(a *Authorizer, result *UpgradeResult) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			result.Auditid, result.Err = u.upgrade(ctx, a)
+			if u.OnResult != nil {
+				u.OnResult(*result)
+			}
+		}(a, &results[i])
+	}
+	wg.Wait()
+	return results
+}
+
+//upgrade 上传代码，设置了Audit时提交审核
+func (u *TemplateUpgrade) upgrade(ctx context.Context, a *Authorizer) (auditid int64, err error) {
+	commit := u.Commit
+	if u.ExtJSON != nil {
+		commit.ExtJSON, err = u.ExtJSON(a)
+		if err != nil {
+			return 0, err
+		}
+	}
+	if u.Audit == nil {
+		return 0, a.CommitContext(ctx, commit)
+	}
+	return a.DeployContext(ctx, commit, *u.Audit)
+}
@@ -0,0 +1,103 @@
+package component_test
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/wei193/component"
+	"github.com/wei193/component/wechattest"
+)
+
+func TestTemplateLibrary(t *testing.T) {
+	srv := wechattest.NewServer()
+	defer srv.Close()
+	c, err := srv.Component(wechattest.ComponentAppid, wechattest.Appsecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	drafts, err := c.GetTemplateDraftList()
+	if err != nil || len(drafts) != 1 || drafts[0].DraftID != 1 {
+		t.Fatalf("GetTemplateDraftList = %+v, %v", drafts, err)
+	}
+	if err = c.AddToTemplate(drafts[0].DraftID, component.TemplateNormal); err != nil {
+		t.Fatal(err)
+	}
+	srv.ExpireTokens()
+	templates, err := c.GetTemplateList(component.TemplateAll)
+	if err != nil || len(templates) != 1 || templates[0].TemplateID != 1 {
+		t.Fatalf("GetTemplateList = %+v, %v", templates, err)
+	}
+	if n := srv.Calls("/wxa/gettemplatelist"); n != 2 {
+		t.Errorf("gettemplatelist calls = %d, want 2 after token refresh", n)
+	}
+	if err = c.DeleteTemplate(templates[0].TemplateID); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUpgrade(t *testing.T) {
+	srv := wechattest.NewServer()
+	defer srv.Close()
+	c, err := srv.Component(wechattest.ComponentAppid, wechattest.Appsecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var authorizers []*component.Authorizer
+	for _, appid := range []string{"wx1", "wx2", "wx3"} {
+		a, err := c.NewAuthorizer(appid, srv.IssueToken(), 1<<62, "REFRESH_"+appid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		authorizers = append(authorizers, a)
+	}
+
+	var (
+		lock     sync.Mutex
+		reported = make(map[string]error)
+	)
+	errExt := errors.New("no ext json")
+	results := c.Upgrade(authorizers, component.TemplateUpgrade{
+		Commit: component.JWxaCommit{TemplateID: 7, UserVersion: "v2.0.0"},
+		ExtJSON: func(a *component.Authorizer) (string, error) {
+			if a.Appid == "wx2" {
+				return "", errExt
+			}
+			return `{"extAppid":"` + a.Appid + `"}`, nil
+		},
+		Audit:       &component.JWxaAudit{},
+		Concurrency: 2,
+		OnResult: func(result component.UpgradeResult) {
+			lock.Lock()
+			reported[result.Appid] = result.Err
+			lock.Unlock()
+		},
+	})
+
+	if len(results) != 3 || len(reported) != 3 {
+		t.Fatalf("results = %+v, reported = %v", results, reported)
+	}
+	for i, r := range results {
+		if r.Appid != authorizers[i].Appid {
+			t.Errorf("results[%d].Appid = %s", i, r.Appid)
+		}
+	}
+	if results[1].Err != errExt || results[0].Err != nil || results[0].Auditid != wechattest.AuditID {
+		t.Errorf("results = %+v", results)
+	}
+	if n := srv.Calls("/wxa/commit"); n != 2 {
+		t.Errorf("commit calls = %d, want 2", n)
+	}
+	for _, r := range srv.Requests() {
+		if r.Path != "/wxa/commit" {
+			continue
+		}
+		var commit component.JWxaCommit
+		json.Unmarshal(r.Body, &commit)
+		if commit.TemplateID != 7 || commit.ExtJSON == "" {
+			t.Errorf("commit = %+v", commit)
+		}
+	}
+}
@@ -2,15 +2,15 @@ package wechat
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
 	"encoding/json"
 	"encoding/xml"
 	"errors"
-	"io/ioutil"
-	"log"
 	"net/http"
 	"net/url"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/wei193/component/common"
@@ -39,7 +39,8 @@ const (
 type ResAccessToken struct {
 	AccessToken string `json:"access_token"`
 	Expiresin   int    `json:"expires_in"`
-	Errcode     string `json:"errcode"`
+	Errcode     int    `json:"errcode"`
+	Errmsg      string `json:"errmsg"`
 }
 
 //ResUserToken 用户Token
@@ -71,6 +72,19 @@ type Wechat struct {
 	JsapiTokenExpires  int64
 	AuthorizedDomain   string
 	Mch                *MchInfo
+	Store              common.TokenStore
+	Locker             common.Locker
+	TokenFunc          TokenFunc
+	Guard              APIGuard
+	Client             *common.Client
+	//Verifier 回调校验，为空时检查5分钟内的timestamp并在内存中记录nonce
+	Verifier *common.Verifier
+
+	tokenLock       sync.Mutex
+	ticketLock      sync.Mutex
+	mchLock         sync.Mutex
+	verifierOnce    sync.Once
+	defaultVerifier *common.Verifier
 }
 
 //MchInfo 微信商户信息
@@ -81,6 +95,8 @@ type MchInfo struct {
 	KeyPath    string
 	CaPath     string
 	_tlsConfig *tls.Config
+	_client    *common.Client
+	_base      *common.Client
 }
 
 //New 创建wechat
@@ -97,6 +113,8 @@ func New(Appid, Appsecret, Token, Encodingaeskey, AuthorizedDomain string) *Wech
 
 //SetMch 设置商户
 func (wx *Wechat) SetMch(mchid, paykey, certpath, keypath, capath string) (err error) {
+	wx.mchLock.Lock()
+	defer wx.mchLock.Unlock()
 	if wx.Mch == nil {
 		wx.Mch = &MchInfo{
 			MchID:  mchid,
@@ -107,52 +125,65 @@ func (wx *Wechat) SetMch(mchid, paykey, certpath, keypath, capath string) (err e
 		wx.Mch.PayKey = paykey
 	}
 	wx.Mch._tlsConfig, err = common.GetTLSConfig(certpath, keypath, capath)
+	wx.Mch._client = nil
 	return err
 }
 
 //GetAccessToken 获取 access_token
 func (wx *Wechat) GetAccessToken() (err error) {
+	return wx.GetAccessTokenContext(context.Background())
+}
+
+//GetAccessTokenContext 同GetAccessToken，使用ctx控制请求的取消和超时
+func (wx *Wechat) GetAccessTokenContext(ctx context.Context) (err error) {
+	_, err = wx.RefreshAccessTokenContext(ctx)
+	return err
+}
+
+//requsetAccessToken 使用appsecret请求新的access_token
+func (wx *Wechat) requsetAccessToken(ctx context.Context) (token string, expiresIn int, err error) {
 	if wx.Appsecret == "" {
-		return errors.New("no secret")
+		return "", 0, errors.New("no secret")
 	}
 	param := make(map[string]string)
 	param["grant_type"] = "client_credential"
 	param["appid"] = wx.Appid
 	param["secret"] = wx.Appsecret
 
-	req, err := http.NewRequest("GET", common.Param(URLTOKEN, param), nil)
-	if err == nil {
-		return err
+	req, err := http.NewRequestWithContext(ctx, "GET", common.Param(URLTOKEN, param), nil)
+	if err != nil {
+		return "", 0, err
 	}
-	resBody, err := common.RequsetJSON(req, -1)
+	resBody, err := wx.APIClient().RequsetJSON(wx.withAppid(req), "", nil)
 	if err != nil {
-		log.Println(err)
-		return err
+		return "", 0, err
 	}
 	var accToken ResAccessToken
 	err = json.Unmarshal(resBody, &accToken)
 	if err != nil {
-		log.Println(err)
-		return err
+		return "", 0, err
 	}
-	if accToken.Errcode != "" {
-		return errors.New("获取Token  失败")
+	if accToken.Errcode != 0 {
+		return "", 0, common.NewAPIError(req, accToken.Errcode, accToken.Errmsg)
 	}
-
-	wx.AccessTokenExpires = time.Now().Unix() + int64(accToken.Expiresin)
-	wx.AccessToken = accToken.AccessToken
-
-	return nil
+	return accToken.AccessToken, accToken.Expiresin, nil
 }
 
 //CheckAccessToken 检查微信access_token有效性
 func (wx *Wechat) CheckAccessToken() (err error) {
-	req, err := http.NewRequest("GET", URLGETCALLBACKIP+"?access_token="+
-		wx.AccessToken, nil)
-	if err == nil {
+	return wx.CheckAccessTokenContext(context.Background())
+}
+
+//CheckAccessTokenContext 同CheckAccessToken，使用ctx控制请求的取消和超时
+func (wx *Wechat) CheckAccessTokenContext(ctx context.Context) (err error) {
+	//检查当前令牌本身，不自动获取或刷新令牌
+	token, _ := wx.CurrentAccessToken()
+	req, err := http.NewRequestWithContext(ctx, "GET", URLGETCALLBACKIP+"?access_token="+
+		url.QueryEscape(token), nil)
+	if err != nil {
 		return err
 	}
-	_, err = common.RequsetJSON(req, 0)
+	_, err = wx.RequsetJSON(req, TOKENIGNORE)
 	if err != nil {
 		return err
 	}
@@ -161,31 +192,46 @@ func (wx *Wechat) CheckAccessToken() (err error) {
 
 //GetJsapiTicket 获取js的jsapi_ticket
 func (wx *Wechat) GetJsapiTicket() (err error) {
+	return wx.GetJsapiTicketContext(context.Background())
+}
+
+//GetJsapiTicketContext 同GetJsapiTicket，使用ctx控制请求的取消和超时
+func (wx *Wechat) GetJsapiTicketContext(ctx context.Context) (err error) {
+	wx.ticketLock.Lock()
+	defer wx.ticketLock.Unlock()
+	unlock, err := common.LockToken(wx.Locker, wx.Appid, common.TokenJsapiTicket)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return wx.refreshJsapiTicket(ctx)
+}
+
+//refreshJsapiTicket 请求新的jsapi_ticket并写入令牌存储
+func (wx *Wechat) refreshJsapiTicket(ctx context.Context) (err error) {
 	param := make(map[string]string)
-	param["access_token"] = wx.AccessToken
+	param["access_token"] = ""
 	param["type"] = "jsapi"
-	req, err := http.NewRequest("GET", common.Param(URLGETTICKET, param), nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", common.Param(URLGETTICKET, param), nil)
 	if err != nil {
 		return err
 	}
 
-	resBody, err := common.RequsetJSON(req, 0)
+	resBody, err := wx.RequsetJSON(req, 0)
 	if err != nil {
-		log.Println(err)
 		return err
 	}
 	var tmpTick resJsTicket
 	err = json.Unmarshal(resBody, &tmpTick)
 	if err != nil {
-		log.Println(err)
 		return err
 	} else if tmpTick.Errcode == 0 {
 		wx.JsapiTokenTime = time.Now().Unix()
 		wx.JsapiTicket = tmpTick.Ticket
 		wx.JsapiTokenExpires = time.Now().Unix() + int64(tmpTick.Expiresin)
-		return nil
+		return wx.saveToken(common.TokenJsapiTicket, wx.JsapiTicket, wx.JsapiTokenExpires)
 	} else {
-		return errors.New(tmpTick.Errmsg)
+		return common.NewAPIError(req, tmpTick.Errcode, tmpTick.Errmsg)
 	}
 }
 
@@ -201,52 +247,119 @@ func (wx *Wechat) CreateJsSignature(url, noncestr string, timestamp int64, data
 	return common.SignSha1(data)
 }
 
-func (wx *Wechat) httpsRequsetXML(req *http.Request, tflag int, isXML ...bool) ([]byte, error) {
-	resBody, err := wx.httpsRequset(req)
+//APIGuard 接口调用前的检查，返回错误时不发送请求
+type APIGuard func(req *http.Request) error
+
+//RequsetJSON 发送带access_token的微信请求，errcode不为0时返回*common.APIError
+//tflag不为TOKENIGNORE时，URL中为空的access_token参数使用ValidAccessToken填充，
+//access_token无效或过期会强制刷新一次并重放请求
+func (wx *Wechat) RequsetJSON(req *http.Request, tflag int) ([]byte, error) {
+	err := wx.checkGuard(req)
 	if err != nil {
 		return nil, err
 	}
-	if len(isXML) == 1 && !isXML[0] {
-		return resBody, nil
+	var refresh common.TokenRefresher
+	if tflag != TOKENIGNORE {
+		refresh = wx.RefreshAccessTokenContext
+		req, err = wx.withToken(req)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return wx.APIClient().RequsetJSON(wx.withAppid(req), "access_token", refresh)
+}
+
+//withToken 使用有效的access_token填充请求URL中为空的access_token参数
+func (wx *Wechat) withToken(req *http.Request) (*http.Request, error) {
+	q := req.URL.Query()
+	if v, ok := q["access_token"]; !ok || (len(v) > 0 && v[0] != "") {
+		return req, nil
 	}
-	var errcode common.XMLError
-	err = xml.Unmarshal(resBody, &errcode)
-	if err != nil ||
-		errcode.ReturnCode != "SUCCESS" ||
-		errcode.ResultCode != "SUCCESS" ||
-		errcode.ErrCode != "" {
-		return resBody, errors.New(string(resBody))
+	token, err := wx.ValidAccessTokenContext(req.Context())
+	if err != nil {
+		return nil, err
+	}
+	q.Set("access_token", token)
+	r := req.Clone(req.Context())
+	r.URL.RawQuery = q.Encode()
+	return r, nil
+}
+
+//APIClient 请求客户端，未设置Client时使用common.DefaultClient
+func (wx *Wechat) APIClient() *common.Client {
+	if wx.Client != nil {
+		return wx.Client
 	}
+	return common.DefaultClient
+}
 
-	return resBody, nil
+//log 输出带appid的日志，敏感字段由common.Client.Log脱敏
+func (wx *Wechat) log(level common.Level, msg string, fields ...common.Field) {
+	wx.APIClient().Log(level, msg, append([]common.Field{common.F("appid", wx.Appid)}, fields...)...)
 }
 
-func (wx *Wechat) httpsRequset(req *http.Request) ([]byte, error) {
-	tlsConfig := wx.Mch._tlsConfig
-	if tlsConfig == nil {
-		return nil, errors.New("init tls Config Error")
+//withAppid 为请求附加appid日志字段
+func (wx *Wechat) withAppid(req *http.Request) *http.Request {
+	return req.WithContext(common.WithLogFields(req.Context(), common.F("appid", wx.Appid)))
+}
+
+//checkGuard 执行接口调用前的检查
+func (wx *Wechat) checkGuard(req *http.Request) error {
+	if wx.Guard == nil {
+		return nil
 	}
-	tr := &http.Transport{TLSClientConfig: tlsConfig}
-	client := &http.Client{Transport: tr}
-	resp, err := client.Do(req)
+	return wx.Guard(req)
+}
+
+func (wx *Wechat) httpsRequsetXML(req *http.Request, tflag int, isXML ...bool) ([]byte, error) {
+	client, err := wx.mchClient()
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-	return ioutil.ReadAll(resp.Body)
+	return client.RequsetXML(wx.withAppid(req), isXML...)
+}
+
+//requsetXML 发送微信支付请求
+func (wx *Wechat) requsetXML(req *http.Request, isXML ...bool) ([]byte, error) {
+	return wx.APIClient().RequsetXML(wx.withAppid(req), isXML...)
 }
 
-//httpsPost  HttpsPost请求
-func (wx *Wechat) httpsPost(url string, xmlContent []byte, ContentType string) (*http.Response, error) {
-	tlsConfig := wx.Mch._tlsConfig
-	if tlsConfig == nil {
+//httpsPost  HttpsPost请求，return_code或result_code失败时返回*common.APIError
+func (wx *Wechat) httpsPost(ctx context.Context, url string, xmlContent []byte, ContentType string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(xmlContent))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", ContentType)
+	return wx.httpsRequsetXML(req, 0)
+}
+
+//mchClient 使用商户证书的请求客户端
+//Transport在首次使用时基于APIClient的Transport创建，之后复用以保持连接
+func (wx *Wechat) mchClient() (*common.Client, error) {
+	wx.mchLock.Lock()
+	defer wx.mchLock.Unlock()
+	if wx.Mch == nil || wx.Mch._tlsConfig == nil {
 		return nil, errors.New("init tls Config Error")
 	}
-	tr := &http.Transport{TLSClientConfig: tlsConfig}
-	client := &http.Client{Transport: tr}
-	return client.Post(url,
-		ContentType,
-		bytes.NewBuffer(xmlContent))
+	base := wx.APIClient()
+	if wx.Mch._client != nil && wx.Mch._base == base {
+		return wx.Mch._client, nil
+	}
+	var tr *http.Transport
+	if t, ok := base.Transport().(*http.Transport); ok {
+		tr = t.Clone()
+	} else {
+		tr = &http.Transport{Proxy: http.ProxyFromEnvironment}
+	}
+	tr.TLSClientConfig = wx.Mch._tlsConfig
+	hc := &http.Client{Transport: tr, Timeout: 60 * time.Second}
+	if base.HTTPClient != nil {
+		hc.Timeout = base.HTTPClient.Timeout
+	}
+	wx.Mch._client = base.WithHTTPClient(hc)
+	wx.Mch._base = base
+	return wx.Mch._client, nil
 }
 
 //GetRedirectUri 获取登录连接
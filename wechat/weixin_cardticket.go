@@ -6,8 +6,8 @@ package wechat
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
-	"log"
 	"net/http"
 
 	"github.com/wei193/component/common"
@@ -184,11 +184,16 @@ type TMultipleCard struct {
 
 //CardCreate 创建卡券
 func (wx *Wechat) CardCreate(card TACard) (cardid string, err error) {
+	return wx.CardCreateContext(context.Background(), card)
+}
+
+//CardCreateContext 同CardCreate，使用ctx控制请求的取消和超时
+func (wx *Wechat) CardCreateContext(ctx context.Context, card TACard) (cardid string, err error) {
 	param := make(map[string]string)
-	param["access_token"] = wx.AccessToken
+	param["access_token"] = ""
 
 	d, _ := json.Marshal(card)
-	req, err := http.NewRequest("POST", common.Param(URLCardCreate, param),
+	req, err := http.NewRequestWithContext(ctx, "POST", common.Param(URLCardCreate, param),
 		bytes.NewReader(d))
 	if err != nil {
 		return "", err
@@ -197,7 +202,7 @@ func (wx *Wechat) CardCreate(card TACard) (cardid string, err error) {
 		CardID string `json:"card_id"`
 	}
 	var data st
-	resBody, err := common.RequsetJSON(req, 0)
+	resBody, err := wx.RequsetJSON(req, 0)
 	if err != nil {
 		return "", err
 	}
@@ -210,8 +215,13 @@ func (wx *Wechat) CardCreate(card TACard) (cardid string, err error) {
 
 //CardPaycell 设置买单接口
 func (wx *Wechat) CardPaycell(cardid string, isopen bool) (err error) {
+	return wx.CardPaycellContext(context.Background(), cardid, isopen)
+}
+
+//CardPaycellContext 同CardPaycell，使用ctx控制请求的取消和超时
+func (wx *Wechat) CardPaycellContext(ctx context.Context, cardid string, isopen bool) (err error) {
 	param := make(map[string]string)
-	param["access_token"] = wx.AccessToken
+	param["access_token"] = ""
 
 	type st struct {
 		CardID string `json:"card_id"`
@@ -222,12 +232,12 @@ func (wx *Wechat) CardPaycell(cardid string, isopen bool) (err error) {
 		IsOpen: isopen,
 	}
 	d, _ := json.Marshal(data)
-	req, err := http.NewRequest("POST", common.Param(URLCardPaycell, param),
+	req, err := http.NewRequestWithContext(ctx, "POST", common.Param(URLCardPaycell, param),
 		bytes.NewReader(d))
 	if err != nil {
 		return err
 	}
-	_, err = common.RequsetJSON(req, 0)
+	_, err = wx.RequsetJSON(req, 0)
 	if err != nil {
 		return err
 	}
@@ -236,8 +246,13 @@ func (wx *Wechat) CardPaycell(cardid string, isopen bool) (err error) {
 
 //CardSelfconsumecell  设置自助核销接口
 func (wx *Wechat) CardSelfconsumecell(cardid string, isopen bool) (err error) {
+	return wx.CardSelfconsumecellContext(context.Background(), cardid, isopen)
+}
+
+//CardSelfconsumecellContext 同CardSelfconsumecell，使用ctx控制请求的取消和超时
+func (wx *Wechat) CardSelfconsumecellContext(ctx context.Context, cardid string, isopen bool) (err error) {
 	param := make(map[string]string)
-	param["access_token"] = wx.AccessToken
+	param["access_token"] = ""
 
 	type st struct {
 		CardID string `json:"card_id"`
@@ -248,12 +263,12 @@ func (wx *Wechat) CardSelfconsumecell(cardid string, isopen bool) (err error) {
 		IsOpen: isopen,
 	}
 	d, _ := json.Marshal(data)
-	req, err := http.NewRequest("POST", common.Param(URLCardSelfconsumecell, param),
+	req, err := http.NewRequestWithContext(ctx, "POST", common.Param(URLCardSelfconsumecell, param),
 		bytes.NewReader(d))
 	if err != nil {
 		return err
 	}
-	_, err = common.RequsetJSON(req, 0)
+	_, err = wx.RequsetJSON(req, 0)
 	if err != nil {
 		return err
 	}
@@ -262,8 +277,13 @@ func (wx *Wechat) CardSelfconsumecell(cardid string, isopen bool) (err error) {
 
 //CardSingleQrcode 创建二维码接口
 func (wx *Wechat) CardSingleQrcode(card TScanCard) (err error) {
+	return wx.CardSingleQrcodeContext(context.Background(), card)
+}
+
+//CardSingleQrcodeContext 同CardSingleQrcode，使用ctx控制请求的取消和超时
+func (wx *Wechat) CardSingleQrcodeContext(ctx context.Context, card TScanCard) (err error) {
 	param := make(map[string]string)
-	param["access_token"] = wx.AccessToken
+	param["access_token"] = ""
 
 	type st struct {
 		ActionName    string      `json:"action_name"`
@@ -278,12 +298,12 @@ func (wx *Wechat) CardSingleQrcode(card TScanCard) (err error) {
 		},
 	}
 	d, _ := json.Marshal(data)
-	req, err := http.NewRequest("POST", common.Param(URLCardQrcode, param),
+	req, err := http.NewRequestWithContext(ctx, "POST", common.Param(URLCardQrcode, param),
 		bytes.NewReader(d))
 	if err != nil {
 		return err
 	}
-	_, err = common.RequsetJSON(req, 0)
+	_, err = wx.RequsetJSON(req, 0)
 	if err != nil {
 		return err
 	}
@@ -292,8 +312,13 @@ func (wx *Wechat) CardSingleQrcode(card TScanCard) (err error) {
 
 //CardMultipleQrcode 创建二维码接口
 func (wx *Wechat) CardMultipleQrcode(cards []TScanCard) (err error) {
+	return wx.CardMultipleQrcodeContext(context.Background(), cards)
+}
+
+//CardMultipleQrcodeContext 同CardMultipleQrcode，使用ctx控制请求的取消和超时
+func (wx *Wechat) CardMultipleQrcodeContext(ctx context.Context, cards []TScanCard) (err error) {
 	param := make(map[string]string)
-	param["access_token"] = wx.AccessToken
+	param["access_token"] = ""
 
 	type st struct {
 		ActionName    string      `json:"action_name"`
@@ -309,12 +334,12 @@ func (wx *Wechat) CardMultipleQrcode(cards []TScanCard) (err error) {
 		},
 	}
 	d, _ := json.Marshal(data)
-	req, err := http.NewRequest("POST", common.Param(URLCardQrcode, param),
+	req, err := http.NewRequestWithContext(ctx, "POST", common.Param(URLCardQrcode, param),
 		bytes.NewReader(d))
 	if err != nil {
 		return err
 	}
-	_, err = common.RequsetJSON(req, 0)
+	_, err = wx.RequsetJSON(req, 0)
 	if err != nil {
 		return err
 	}
@@ -323,8 +348,13 @@ func (wx *Wechat) CardMultipleQrcode(cards []TScanCard) (err error) {
 
 //CardCodeGet 查询Code接口
 func (wx *Wechat) CardCodeGet(code string, cardid string, checkConsume bool) (err error) {
+	return wx.CardCodeGetContext(context.Background(), code, cardid, checkConsume)
+}
+
+//CardCodeGetContext 同CardCodeGet，使用ctx控制请求的取消和超时
+func (wx *Wechat) CardCodeGetContext(ctx context.Context, code string, cardid string, checkConsume bool) (err error) {
 	param := make(map[string]string)
-	param["access_token"] = wx.AccessToken
+	param["access_token"] = ""
 
 	type st struct {
 		Code         string `json:"code"`
@@ -337,12 +367,12 @@ func (wx *Wechat) CardCodeGet(code string, cardid string, checkConsume bool) (er
 		CheckConsume: checkConsume,
 	}
 	d, _ := json.Marshal(data)
-	req, err := http.NewRequest("POST", common.Param(URLCardCodeGet, param),
+	req, err := http.NewRequestWithContext(ctx, "POST", common.Param(URLCardCodeGet, param),
 		bytes.NewReader(d))
 	if err != nil {
 		return err
 	}
-	_, err = common.RequsetJSON(req, 0)
+	_, err = wx.RequsetJSON(req, 0)
 	if err != nil {
 		return err
 	}
@@ -351,8 +381,13 @@ func (wx *Wechat) CardCodeGet(code string, cardid string, checkConsume bool) (er
 
 //CardUserGetcardlist 获取用户已领取卡券接口
 func (wx *Wechat) CardUserGetcardlist(openid string, cardid string) (err error) {
+	return wx.CardUserGetcardlistContext(context.Background(), openid, cardid)
+}
+
+//CardUserGetcardlistContext 同CardUserGetcardlist，使用ctx控制请求的取消和超时
+func (wx *Wechat) CardUserGetcardlistContext(ctx context.Context, openid string, cardid string) (err error) {
 	param := make(map[string]string)
-	param["access_token"] = wx.AccessToken
+	param["access_token"] = ""
 
 	type st struct {
 		Openid string `json:"openid"`
@@ -363,12 +398,12 @@ func (wx *Wechat) CardUserGetcardlist(openid string, cardid string) (err error)
 		CardID: cardid,
 	}
 	d, _ := json.Marshal(data)
-	req, err := http.NewRequest("POST", common.Param(URLCardUserGetcardlist, param),
+	req, err := http.NewRequestWithContext(ctx, "POST", common.Param(URLCardUserGetcardlist, param),
 		bytes.NewReader(d))
 	if err != nil {
 		return err
 	}
-	_, err = common.RequsetJSON(req, 0)
+	_, err = wx.RequsetJSON(req, 0)
 	if err != nil {
 		return err
 	}
@@ -377,8 +412,13 @@ func (wx *Wechat) CardUserGetcardlist(openid string, cardid string) (err error)
 
 //CardGet 查看卡券详情
 func (wx *Wechat) CardGet(cardid string) (err error) {
+	return wx.CardGetContext(context.Background(), cardid)
+}
+
+//CardGetContext 同CardGet，使用ctx控制请求的取消和超时
+func (wx *Wechat) CardGetContext(ctx context.Context, cardid string) (err error) {
 	param := make(map[string]string)
-	param["access_token"] = wx.AccessToken
+	param["access_token"] = ""
 
 	type st struct {
 		CardID string `json:"card_id"`
@@ -387,12 +427,12 @@ func (wx *Wechat) CardGet(cardid string) (err error) {
 		CardID: cardid,
 	}
 	d, _ := json.Marshal(data)
-	req, err := http.NewRequest("POST", common.Param(URLCardGet, param),
+	req, err := http.NewRequestWithContext(ctx, "POST", common.Param(URLCardGet, param),
 		bytes.NewReader(d))
 	if err != nil {
 		return err
 	}
-	_, err = common.RequsetJSON(req, 0)
+	_, err = wx.RequsetJSON(req, 0)
 	if err != nil {
 		return err
 	}
@@ -401,8 +441,13 @@ func (wx *Wechat) CardGet(cardid string) (err error) {
 
 //CardBatchget 批量查询卡券列表
 func (wx *Wechat) CardBatchget(statusList []string, offset, count int) (err error) {
+	return wx.CardBatchgetContext(context.Background(), statusList, offset, count)
+}
+
+//CardBatchgetContext 同CardBatchget，使用ctx控制请求的取消和超时
+func (wx *Wechat) CardBatchgetContext(ctx context.Context, statusList []string, offset, count int) (err error) {
 	param := make(map[string]string)
-	param["access_token"] = wx.AccessToken
+	param["access_token"] = ""
 
 	type st struct {
 		Offset     int      `json:"offset"`
@@ -415,15 +460,14 @@ func (wx *Wechat) CardBatchget(statusList []string, offset, count int) (err erro
 		StatusList: statusList,
 	}
 	d, _ := json.Marshal(data)
-	req, err := http.NewRequest("POST", common.Param(URLCardBatchget, param),
+	req, err := http.NewRequestWithContext(ctx, "POST", common.Param(URLCardBatchget, param),
 		bytes.NewReader(d))
 	if err != nil {
 		return err
 	}
-	body, err := common.RequsetJSON(req, 0)
+	_, err = wx.RequsetJSON(req, 0)
 	if err != nil {
 		return err
 	}
-	log.Println(string(body))
 	return nil
 }
@@ -1,4 +1,4 @@
-package wechat
+package wechat_test
 
 import "testing"
 
@@ -7,10 +7,12 @@ func TestAddCardTicket(t *testing.T) {
 }
 
 func TestListdCardTicket(t *testing.T) {
-	err := testWx.CardBatchget(nil, 0, 100)
+	srv, wx := newTestWechat(t)
+	defer srv.Close()
+
+	err := wx.CardBatchget(nil, 0, 100)
 	if err != nil {
 		t.Error(err)
 		return
 	}
-
 }
@@ -2,10 +2,10 @@ package wechat
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"io"
-	"log"
 	"mime/multipart"
 	"net/http"
 	"os"
@@ -99,22 +99,25 @@ type ResNews struct {
 
 //AddTempMaterial 增加临时资源
 func (wx *Wechat) AddTempMaterial(mediaType, filepath string) (data ReqMedia, err error) {
+	return wx.AddTempMaterialContext(context.Background(), mediaType, filepath)
+}
+
+//AddTempMaterialContext 同AddTempMaterial，使用ctx控制请求的取消和超时
+func (wx *Wechat) AddTempMaterialContext(ctx context.Context, mediaType, filepath string) (data ReqMedia, err error) {
 	param := make(map[string]string)
-	param["access_token"] = wx.AccessToken
+	param["access_token"] = ""
 	param["type"] = mediaType
-	req, err := newfileUploadRequest(common.Param(URLMediaUpload, param), nil,
+	req, err := newfileUploadRequest(ctx, common.Param(URLMediaUpload, param), nil,
 		"media", filepath)
 	if err != nil {
 		return
 	}
-	resBody, err := common.RequsetJSON(req, 0)
+	resBody, err := wx.RequsetJSON(req, 0)
 	if err != nil {
-		log.Println(err)
 		return data, err
 	}
 	err = json.Unmarshal(resBody, &data)
 	if err != nil {
-		log.Println(err)
 		return data, err
 	}
 	return data, nil
@@ -122,25 +125,38 @@ func (wx *Wechat) AddTempMaterial(mediaType, filepath string) (data ReqMedia, er
 
 //GetTempMaterial 获取临时的媒体资源
 func (wx *Wechat) GetTempMaterial(basepath, mediaid string) (string, error) {
+	return wx.GetTempMaterialContext(context.Background(), basepath, mediaid)
+}
+
+//GetTempMaterialContext 同GetTempMaterial，使用ctx控制请求的取消和超时
+func (wx *Wechat) GetTempMaterialContext(ctx context.Context, basepath, mediaid string) (string, error) {
 	param := make(map[string]string)
-	param["access_token"] = wx.AccessToken
+	param["access_token"] = ""
 	param["media_id"] = mediaid
 
-	req, err := http.NewRequest("GET", common.Param(URLMediaGet, param), nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", common.Param(URLMediaGet, param), nil)
+	if err != nil {
+		return "", err
+	}
+	err = wx.checkGuard(req)
+	if err != nil {
+		return "", err
+	}
+	req, err = wx.withToken(req)
 	if err != nil {
 		return "", err
 	}
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := wx.APIClient().Do(req)
 	if err != nil {
-		log.Println(err)
+		return "", err
 	}
+	defer resp.Body.Close()
 	fileName := ""
 	Disposition := resp.Header.Get("Content-Disposition")
 	if Disposition != "" {
 		if strings.Index(Disposition, `attachment; filename="`) == 0 {
 			fileName = Disposition[len(`attachment; filename="`) : len(Disposition)-1]
-			log.Println(fileName)
+			wx.log(common.LevelDebug, "media saved", common.F("file", fileName))
 		}
 	}
 	if fileName == "" {
@@ -149,13 +165,11 @@ func (wx *Wechat) GetTempMaterial(basepath, mediaid string) (string, error) {
 	fileName = path.Join(basepath, fileName)
 	file, err := os.Create(fileName)
 	if err != nil {
-		log.Println(err)
 		return fileName, err
 	}
 	defer file.Close()
 	_, err = io.Copy(file, resp.Body)
 	if err != nil {
-		log.Println(err)
 		return fileName, err
 	}
 	return fileName, err
@@ -163,28 +177,30 @@ func (wx *Wechat) GetTempMaterial(basepath, mediaid string) (string, error) {
 
 //AddNews 增加图文消息
 func (wx *Wechat) AddNews(news []TNews) (data ReqMedia, err error) {
+	return wx.AddNewsContext(context.Background(), news)
+}
+
+//AddNewsContext 同AddNews，使用ctx控制请求的取消和超时
+func (wx *Wechat) AddNewsContext(ctx context.Context, news []TNews) (data ReqMedia, err error) {
 	param := make(map[string]string)
-	param["access_token"] = wx.AccessToken
+	param["access_token"] = ""
 
 	type articles struct {
 		Articles []TNews `json:"articles"`
 	}
 	t := articles{news}
 	d, _ := json.Marshal(t)
-	req, err := http.NewRequest("POST", common.Param(URLMediaAddNews, param),
+	req, err := http.NewRequestWithContext(ctx, "POST", common.Param(URLMediaAddNews, param),
 		bytes.NewReader(d))
 	if err != nil {
-		log.Println(err)
 		return data, err
 	}
-	resBody, err := common.RequsetJSON(req, 0)
+	resBody, err := wx.RequsetJSON(req, 0)
 	if err != nil {
-		log.Println(err)
 		return data, err
 	}
 	err = json.Unmarshal(resBody, &data)
 	if err != nil {
-		log.Println(err)
 		return data, err
 	}
 	return data, nil
@@ -193,8 +209,13 @@ func (wx *Wechat) AddNews(news []TNews) (data ReqMedia, err error) {
 
 //UpdateNews 修改图文消息
 func (wx *Wechat) UpdateNews(Mediaid string, Index int, news TNews) int {
+	return wx.UpdateNewsContext(context.Background(), Mediaid, Index, news)
+}
+
+//UpdateNewsContext 同UpdateNews，使用ctx控制请求的取消和超时
+func (wx *Wechat) UpdateNewsContext(ctx context.Context, Mediaid string, Index int, news TNews) int {
 	param := make(map[string]string)
-	param["access_token"] = wx.AccessToken
+	param["access_token"] = ""
 
 	type articles struct {
 		Mediaid  string `json:"media_id"`
@@ -203,12 +224,12 @@ func (wx *Wechat) UpdateNews(Mediaid string, Index int, news TNews) int {
 	}
 	t := articles{Mediaid, Index, news}
 	d, _ := json.Marshal(t)
-	req, err := http.NewRequest("POST", common.Param(URLMediaUpdateNews, param),
+	req, err := http.NewRequestWithContext(ctx, "POST", common.Param(URLMediaUpdateNews, param),
 		bytes.NewReader(d))
 	if err != nil {
 		return 0
 	}
-	_, err = common.RequsetJSON(req, 0)
+	_, err = wx.RequsetJSON(req, 0)
 	if err != nil {
 		return 0
 	}
@@ -217,22 +238,25 @@ func (wx *Wechat) UpdateNews(Mediaid string, Index int, news TNews) int {
 
 //UploadImg 增加图文消息图片
 func (wx *Wechat) UploadImg(filepath string) (data ReqMedia, err error) {
+	return wx.UploadImgContext(context.Background(), filepath)
+}
+
+//UploadImgContext 同UploadImg，使用ctx控制请求的取消和超时
+func (wx *Wechat) UploadImgContext(ctx context.Context, filepath string) (data ReqMedia, err error) {
 	param := make(map[string]string)
-	param["access_token"] = wx.AccessToken
+	param["access_token"] = ""
 
-	req, err := newfileUploadRequest(common.Param(URLMediaUploadImg, param),
+	req, err := newfileUploadRequest(ctx, common.Param(URLMediaUploadImg, param),
 		nil, "media", filepath)
 	if err != nil {
 		return
 	}
-	resBody, err := common.RequsetJSON(req, 0)
+	resBody, err := wx.RequsetJSON(req, 0)
 	if err != nil {
-		log.Println(err)
 		return data, err
 	}
 	err = json.Unmarshal(resBody, &data)
 	if err != nil {
-		log.Println(err)
 		return data, err
 	}
 	return data, nil
@@ -240,23 +264,26 @@ func (wx *Wechat) UploadImg(filepath string) (data ReqMedia, err error) {
 
 //AddMaterial 增加资源
 func (wx *Wechat) AddMaterial(mediaType, filepath string) (data ReqMedia, err error) {
+	return wx.AddMaterialContext(context.Background(), mediaType, filepath)
+}
+
+//AddMaterialContext 同AddMaterial，使用ctx控制请求的取消和超时
+func (wx *Wechat) AddMaterialContext(ctx context.Context, mediaType, filepath string) (data ReqMedia, err error) {
 	param := make(map[string]string)
-	param["access_token"] = wx.AccessToken
+	param["access_token"] = ""
 	param["type"] = mediaType
 
-	req, err := newfileUploadRequest(common.Param(URLMediaAddMaterial, param),
+	req, err := newfileUploadRequest(ctx, common.Param(URLMediaAddMaterial, param),
 		nil, "media", filepath)
 	if err != nil {
 		return
 	}
-	resBody, err := common.RequsetJSON(req, 0)
+	resBody, err := wx.RequsetJSON(req, 0)
 	if err != nil {
-		log.Println(err)
 		return data, err
 	}
 	err = json.Unmarshal(resBody, &data)
 	if err != nil {
-		log.Println(err)
 		return data, err
 	}
 	return data, nil
@@ -264,46 +291,62 @@ func (wx *Wechat) AddMaterial(mediaType, filepath string) (data ReqMedia, err er
 
 //DelMaterial 删除资源
 func (wx *Wechat) DelMaterial(mediaid string) int {
+	return wx.DelMaterialContext(context.Background(), mediaid)
+}
+
+//DelMaterialContext 同DelMaterial，使用ctx控制请求的取消和超时
+func (wx *Wechat) DelMaterialContext(ctx context.Context, mediaid string) int {
 	type stTmp struct {
 		Mediaid string `json:"media_id"`
 	}
 	t := stTmp{mediaid}
 	d, _ := json.Marshal(t)
-	req, err := http.NewRequest("POST", URLMediaDelMaterial+"?access_token="+
-		wx.AccessToken, bytes.NewReader(d))
-	if err == nil {
-		return 1
+	req, err := http.NewRequestWithContext(ctx, "POST", URLMediaDelMaterial+"?access_token=", bytes.NewReader(d))
+	if err != nil {
+		return 0
 	}
-	_, err = common.RequsetJSON(req, 0)
-	if err == nil {
-		return 1
+	_, err = wx.RequsetJSON(req, 0)
+	if err != nil {
+		return 0
 	}
-	return 0
+	return 1
 }
 
 //GetMaterial 获取除文章和视频类型外的媒体资源
 func (wx *Wechat) GetMaterial(basepath, mediaid string) (string, error) {
+	return wx.GetMaterialContext(context.Background(), basepath, mediaid)
+}
+
+//GetMaterialContext 同GetMaterial，使用ctx控制请求的取消和超时
+func (wx *Wechat) GetMaterialContext(ctx context.Context, basepath, mediaid string) (string, error) {
 	type stTmp struct {
 		Mediaid string `json:"media_id"`
 	}
 	t := stTmp{mediaid}
 	d, _ := json.Marshal(t)
-	req, err := http.NewRequest("POST", URLMediaGetMaterial+"?access_token="+
-		wx.AccessToken, bytes.NewReader(d))
-	if err == nil {
+	req, err := http.NewRequestWithContext(ctx, "POST", URLMediaGetMaterial+"?access_token=", bytes.NewReader(d))
+	if err != nil {
+		return "", err
+	}
+	err = wx.checkGuard(req)
+	if err != nil {
+		return "", err
+	}
+	req, err = wx.withToken(req)
+	if err != nil {
 		return "", err
 	}
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := wx.APIClient().Do(req)
 	if err != nil {
-		log.Println(err)
+		return "", err
 	}
+	defer resp.Body.Close()
 	fileName := ""
 	Disposition := resp.Header.Get("Content-Disposition")
 	if Disposition != "" {
 		if strings.Index(Disposition, `attachment; filename="`) == 0 {
 			fileName = Disposition[len(`attachment; filename="`) : len(Disposition)-1]
-			log.Println(fileName)
+			wx.log(common.LevelDebug, "media saved", common.F("file", fileName))
 		}
 	}
 	if fileName == "" {
@@ -312,13 +355,11 @@ func (wx *Wechat) GetMaterial(basepath, mediaid string) (string, error) {
 	fileName = path.Join(basepath, fileName)
 	file, err := os.Create(fileName)
 	if err != nil {
-		log.Println(err)
 		return fileName, err
 	}
 	defer file.Close()
 	_, err = io.Copy(file, resp.Body)
 	if err != nil {
-		log.Println(err)
 		return fileName, err
 	}
 	return fileName, err
@@ -326,6 +367,11 @@ func (wx *Wechat) GetMaterial(basepath, mediaid string) (string, error) {
 
 //GetMaterialsNews 获取图文资源
 func (wx *Wechat) GetMaterialsNews(Type string, offset, count int) (data ResNews, err error) {
+	return wx.GetMaterialsNewsContext(context.Background(), Type, offset, count)
+}
+
+//GetMaterialsNewsContext 同GetMaterialsNews，使用ctx控制请求的取消和超时
+func (wx *Wechat) GetMaterialsNewsContext(ctx context.Context, Type string, offset, count int) (data ResNews, err error) {
 	type stTmp struct {
 		Type   string `json:"type"`
 		Offset int    `json:"offset"`
@@ -333,19 +379,16 @@ func (wx *Wechat) GetMaterialsNews(Type string, offset, count int) (data ResNews
 	}
 	t := stTmp{Type, offset, count}
 	d, _ := json.Marshal(t)
-	req, err := http.NewRequest("POST", URLMediaBatchgetMaterial+"?access_token="+
-		wx.AccessToken, bytes.NewReader(d))
-	if err == nil {
+	req, err := http.NewRequestWithContext(ctx, "POST", URLMediaBatchgetMaterial+"?access_token=", bytes.NewReader(d))
+	if err != nil {
 		return data, err
 	}
-	resBody, err := common.RequsetJSON(req, 0)
+	resBody, err := wx.RequsetJSON(req, 0)
 	if err != nil {
-		log.Println(err)
 		return data, err
 	}
 	err = json.Unmarshal(resBody, &data)
 	if err != nil {
-		log.Println(err)
 		return data, err
 	}
 	return data, err
@@ -353,6 +396,11 @@ func (wx *Wechat) GetMaterialsNews(Type string, offset, count int) (data ResNews
 
 //GetMaterials 获取资源
 func (wx *Wechat) GetMaterials(Type string, offset, count int) (data TMaterialList, err error) {
+	return wx.GetMaterialsContext(context.Background(), Type, offset, count)
+}
+
+//GetMaterialsContext 同GetMaterials，使用ctx控制请求的取消和超时
+func (wx *Wechat) GetMaterialsContext(ctx context.Context, Type string, offset, count int) (data TMaterialList, err error) {
 	type stTmp struct {
 		Type   string `json:"type"`
 		Offset int    `json:"offset"`
@@ -360,20 +408,16 @@ func (wx *Wechat) GetMaterials(Type string, offset, count int) (data TMaterialLi
 	}
 	t := stTmp{Type, offset, count}
 	d, _ := json.Marshal(t)
-	req, err := http.NewRequest("POST", URLMediaBatchgetMaterial+"?access_token="+
-		wx.AccessToken, bytes.NewReader(d))
-	if err == nil {
+	req, err := http.NewRequestWithContext(ctx, "POST", URLMediaBatchgetMaterial+"?access_token=", bytes.NewReader(d))
+	if err != nil {
 		return data, err
 	}
-	resBody, err := common.RequsetJSON(req, 0)
+	resBody, err := wx.RequsetJSON(req, 0)
 	if err != nil {
-		log.Println(err)
 		return data, err
 	}
-	//	log.Println(string(resBody))
 	err = json.Unmarshal(resBody, &data)
 	if err != nil {
-		log.Println(err)
 		return data, err
 	}
 	return data, err
@@ -381,9 +425,14 @@ func (wx *Wechat) GetMaterials(Type string, offset, count int) (data TMaterialLi
 
 //GetAllMaterials 获取除文章类型外的资源列表
 func (wx *Wechat) GetAllMaterials(Type string) (materials []TMaterial, err error) {
+	return wx.GetAllMaterialsContext(context.Background(), Type)
+}
+
+//GetAllMaterialsContext 同GetAllMaterials，使用ctx控制请求的取消和超时
+func (wx *Wechat) GetAllMaterialsContext(ctx context.Context, Type string) (materials []TMaterial, err error) {
 	offset := 0
 	for {
-		data, err := wx.GetMaterials(Type, offset, 20)
+		data, err := wx.GetMaterialsContext(ctx, Type, offset, 20)
 		if err != nil {
 			return materials, err
 		}
@@ -398,9 +447,14 @@ func (wx *Wechat) GetAllMaterials(Type string) (materials []TMaterial, err error
 
 //GetAllMaterialsNews 获取文章类型资源列表
 func (wx *Wechat) GetAllMaterialsNews() (materials []TNewsItem, err error) {
+	return wx.GetAllMaterialsNewsContext(context.Background())
+}
+
+//GetAllMaterialsNewsContext 同GetAllMaterialsNews，使用ctx控制请求的取消和超时
+func (wx *Wechat) GetAllMaterialsNewsContext(ctx context.Context) (materials []TNewsItem, err error) {
 	offset := 0
 	for {
-		data, err := wx.GetMaterialsNews("news", offset, offset+20)
+		data, err := wx.GetMaterialsNewsContext(ctx, "news", offset, offset+20)
 		if err != nil {
 			return materials, err
 		}
@@ -414,7 +468,7 @@ func (wx *Wechat) GetAllMaterialsNews() (materials []TNewsItem, err error) {
 }
 
 //微信文件上传
-func newfileUploadRequest(uri string, params map[string]string, paramName, path string) (*http.Request, error) {
+func newfileUploadRequest(ctx context.Context, uri string, params map[string]string, paramName, path string) (*http.Request, error) {
 	file, err := os.Open(path)
 	if err != nil {
 		return nil, err
@@ -428,7 +482,7 @@ func newfileUploadRequest(uri string, params map[string]string, paramName, path
 		return nil, err
 	}
 	_, err = io.Copy(part, file)
-	if err == nil {
+	if err != nil {
 		return nil, err
 	}
 	for key, val := range params {
@@ -439,7 +493,7 @@ func newfileUploadRequest(uri string, params map[string]string, paramName, path
 		return nil, err
 	}
 
-	req, err := http.NewRequest("POST", uri, body)
+	req, err := http.NewRequestWithContext(ctx, "POST", uri, body)
 	req.Header.Add("Content-Type", writer.FormDataContentType())
 	return req, err
 }
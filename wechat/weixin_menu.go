@@ -2,8 +2,8 @@ package wechat
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
-	"log"
 	"net/http"
 	"strconv"
 
@@ -60,22 +60,24 @@ type STMenus struct {
 
 //GetMenu 获取菜单
 func (wx *Wechat) GetMenu() (data STMenus, err error) {
+	return wx.GetMenuContext(context.Background())
+}
+
+//GetMenuContext 同GetMenu，使用ctx控制请求的取消和超时
+func (wx *Wechat) GetMenuContext(ctx context.Context) (data STMenus, err error) {
 	param := make(map[string]string)
-	param["access_token"] = wx.AccessToken
+	param["access_token"] = ""
 
-	req, err := http.NewRequest("GET", common.Param(URLMENUGET, param), nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", common.Param(URLMENUGET, param), nil)
 	if err != nil {
-		log.Println(err)
 		return data, err
 	}
-	resp, err := common.RequsetJSON(req, 0)
+	resp, err := wx.RequsetJSON(req, 0)
 	if err != nil {
-		log.Println(err)
 		return data, err
 	}
 	err = json.Unmarshal(resp, &data)
 	if err != nil {
-		log.Println(err)
 		return data, err
 	}
 	return data, err
@@ -83,8 +85,13 @@ func (wx *Wechat) GetMenu() (data STMenus, err error) {
 
 //CreatMenu 创建普通菜单
 func (wx *Wechat) CreatMenu(menu STMenu) int {
+	return wx.CreatMenuContext(context.Background(), menu)
+}
+
+//CreatMenuContext 同CreatMenu，使用ctx控制请求的取消和超时
+func (wx *Wechat) CreatMenuContext(ctx context.Context, menu STMenu) int {
 	param := make(map[string]string)
-	param["access_token"] = wx.AccessToken
+	param["access_token"] = ""
 
 	d, _ := json.Marshal(menu)
 	d = bytes.Replace(d, []byte("\\u0026"), []byte("&"), -1)
@@ -92,15 +99,15 @@ func (wx *Wechat) CreatMenu(menu STMenu) int {
 	d = bytes.Replace(d, []byte("\\u003e"), []byte(">"), -1)
 	d = bytes.Replace(d, []byte("\\u003d"), []byte("="), -1)
 
-	req, err := http.NewRequest("POST", common.Param("https://api.weixin.qq.com/cgi-bin/menu/create", param),
+	req, err := http.NewRequestWithContext(ctx, "POST", common.Param("https://api.weixin.qq.com/cgi-bin/menu/create", param),
 		bytes.NewReader(d))
 	if err != nil {
-		log.Println(err)
+		wx.log(common.LevelError, "CreatMenu failed", common.F("error", err))
 		return 0
 	}
-	_, err = common.RequsetJSON(req, 0)
+	_, err = wx.RequsetJSON(req, 0)
 	if err != nil {
-		log.Println(err)
+		wx.log(common.LevelError, "CreatMenu failed", common.F("error", err))
 		return 0
 	}
 	return 1
@@ -108,19 +115,22 @@ func (wx *Wechat) CreatMenu(menu STMenu) int {
 
 //CreatConditionalMenu 创建自定义菜单
 func (wx *Wechat) CreatConditionalMenu(menu STCondMenu) (string, error) {
+	return wx.CreatConditionalMenuContext(context.Background(), menu)
+}
+
+//CreatConditionalMenuContext 同CreatConditionalMenu，使用ctx控制请求的取消和超时
+func (wx *Wechat) CreatConditionalMenuContext(ctx context.Context, menu STCondMenu) (string, error) {
 	param := make(map[string]string)
-	param["access_token"] = wx.AccessToken
+	param["access_token"] = ""
 
 	d, _ := json.Marshal(menu)
-	req, err := http.NewRequest("POST", common.Param("https://api.weixin.qq.com/cgi-bin/menu/addconditional", param),
+	req, err := http.NewRequestWithContext(ctx, "POST", common.Param("https://api.weixin.qq.com/cgi-bin/menu/addconditional", param),
 		bytes.NewReader(d))
 	if err != nil {
-		log.Println(err)
 		return "", err
 	}
-	resBody, err := common.RequsetJSON(req, 0)
+	resBody, err := wx.RequsetJSON(req, 0)
 	if err != nil {
-		log.Println(err)
 		return "", err
 	}
 	type stTmp struct {
@@ -129,7 +139,6 @@ func (wx *Wechat) CreatConditionalMenu(menu STCondMenu) (string, error) {
 	var temp stTmp
 	err = json.Unmarshal(resBody, &temp)
 	if err != nil {
-		log.Println(err)
 		return "", err
 	}
 	return strconv.Itoa(temp.Menuid), nil
@@ -137,22 +146,27 @@ func (wx *Wechat) CreatConditionalMenu(menu STCondMenu) (string, error) {
 
 //DeleteConditionalMenu 删除自定义菜单
 func (wx *Wechat) DeleteConditionalMenu(menuid string) int {
+	return wx.DeleteConditionalMenuContext(context.Background(), menuid)
+}
+
+//DeleteConditionalMenuContext 同DeleteConditionalMenu，使用ctx控制请求的取消和超时
+func (wx *Wechat) DeleteConditionalMenuContext(ctx context.Context, menuid string) int {
 	param := make(map[string]string)
-	param["access_token"] = wx.AccessToken
+	param["access_token"] = ""
 
 	type stTmp struct {
 		Menuid string `json:"menuid"`
 	}
 	temp := stTmp{menuid}
 	d, _ := json.Marshal(temp)
-	req, err := http.NewRequest("GET", common.Param("https://api.weixin.qq.com/cgi-bin/menu/delconditional", param), bytes.NewReader(d))
+	req, err := http.NewRequestWithContext(ctx, "GET", common.Param("https://api.weixin.qq.com/cgi-bin/menu/delconditional", param), bytes.NewReader(d))
 	if err != nil {
-		log.Println(err)
+		wx.log(common.LevelError, "DeleteConditionalMenu failed", common.F("error", err))
 		return 0
 	}
-	_, err = common.RequsetJSON(req, 0)
+	_, err = wx.RequsetJSON(req, 0)
 	if err != nil {
-		log.Println(err)
+		wx.log(common.LevelError, "DeleteConditionalMenu failed", common.F("error", err))
 		return 0
 	}
 	return 1
@@ -160,20 +174,24 @@ func (wx *Wechat) DeleteConditionalMenu(menuid string) int {
 
 //DeleteAllMenu 删除所有菜单
 func (wx *Wechat) DeleteAllMenu() int {
+	return wx.DeleteAllMenuContext(context.Background())
+}
+
+//DeleteAllMenuContext 同DeleteAllMenu，使用ctx控制请求的取消和超时
+func (wx *Wechat) DeleteAllMenuContext(ctx context.Context) int {
 	param := make(map[string]string)
-	param["access_token"] = wx.AccessToken
+	param["access_token"] = ""
 
-	req, err := http.NewRequest("GET", common.Param("https://api.weixin.qq.com/cgi-bin/menu/delete", param), nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", common.Param("https://api.weixin.qq.com/cgi-bin/menu/delete", param), nil)
 	if err != nil {
-		log.Println(err)
+		wx.log(common.LevelError, "DeleteAllMenu failed", common.F("error", err))
 		return 0
 	}
-	_, err = common.RequsetJSON(req, 0)
+	_, err = wx.RequsetJSON(req, 0)
 	if err != nil {
-		log.Println(err)
+		wx.log(common.LevelError, "DeleteAllMenu failed", common.F("error", err))
 		return 0
 	}
-	//	log.Println(string(resp))
 	return 1
 }
 
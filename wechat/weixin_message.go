@@ -2,13 +2,13 @@ package wechat
 
 import (
 	"bytes"
+	"context"
 	"crypto/sha1"
 	"encoding/json"
 	"encoding/xml"
 	"errors"
 	"fmt"
 	"io"
-	"log"
 	"net/http"
 	"sort"
 	"strings"
@@ -59,6 +59,12 @@ type STMsgRequest struct {
 	Ticket               string
 	Title                string
 	Description          string
+	//小程序审核结果事件
+	Reason     string
+	SuccTime   int64
+	FailTime   int64
+	DelayTime  int64
+	ScreenShot string
 }
 
 //STMediaid 媒体ID
@@ -234,26 +240,46 @@ func createResponse(req *STMsgRequest, auto STAutoReply) (resp interface{}, err
 	return nil, nil
 }
 
-//CheckSignature 检查微信消息签名
+//CheckSignature 检查微信消息签名，不检查timestamp和nonce，处理回调时应使用VerifySignature
 func (wx *Wechat) CheckSignature(signature, timestamp, nonce string) bool {
+	return common.EqualSignature(signature, wx.signature(timestamp, nonce))
+}
+
+//VerifySignature 校验微信消息签名、timestamp偏差及nonce是否重放
+func (wx *Wechat) VerifySignature(signature, timestamp, nonce string) error {
+	return wx.verifier().Verify(signature, wx.signature(timestamp, nonce), timestamp, nonce)
+}
 
+//signature 计算消息签名
+func (wx *Wechat) signature(timestamp, nonce string) string {
 	tmps := []string{wx.Token, timestamp, nonce}
 	sort.Strings(tmps)
 	tmpStr := strings.Join(tmps, "")
 	t := sha1.New()
 	io.WriteString(t, tmpStr)
-	tmp := fmt.Sprintf("%x", t.Sum(nil))
+	return fmt.Sprintf("%x", t.Sum(nil))
+}
 
-	if tmp == signature {
-		return true
+//verifier 回调校验器，未设置Verifier时使用默认偏差和内存nonce缓存
+func (wx *Wechat) verifier() *common.Verifier {
+	if wx.Verifier != nil {
+		return wx.Verifier
 	}
-	return false
+	wx.verifierOnce.Do(func() {
+		wx.defaultVerifier = common.NewVerifier()
+	})
+	return wx.defaultVerifier
 }
 
 //SendAll https://api.weixin.qq.com/cgi-bin/message/mass/sendall?access_token=ACCESS_TOKEN
 func (wx *Wechat) SendAll(Tagid int, Msgtype, Content string) (sendData ResMsg, err error) {
+	return wx.SendAllContext(context.Background(), Tagid, Msgtype, Content)
+}
+
+//SendAllContext 同SendAll，使用ctx控制请求的取消和超时
+func (wx *Wechat) SendAllContext(ctx context.Context, Tagid int, Msgtype, Content string) (sendData ResMsg, err error) {
 	param := make(map[string]string)
-	param["access_token"] = wx.AccessToken
+	param["access_token"] = ""
 
 	var Filter STFilter
 	if Tagid == 0 {
@@ -309,21 +335,17 @@ func (wx *Wechat) SendAll(Tagid int, Msgtype, Content string) (sendData ResMsg,
 	}
 
 	d, _ := json.Marshal(data)
-	log.Println(string(d))
-	req, err := http.NewRequest("POST", common.Param("https://api.weixin.qq.com/cgi-bin/message/mass/sendall", param),
+	req, err := http.NewRequestWithContext(ctx, "POST", common.Param("https://api.weixin.qq.com/cgi-bin/message/mass/sendall", param),
 		bytes.NewReader(d))
 	if err != nil {
-		log.Println(err)
 		return sendData, err
 	}
-	resp, err := common.RequsetJSON(req, 0)
+	resp, err := wx.RequsetJSON(req, 0)
 	if err != nil {
-		log.Println(err, string(resp))
 		return sendData, err
 	}
 	err = json.Unmarshal(resp, &sendData)
 	if err != nil {
-		log.Println(err)
 		return sendData, err
 	}
 	return sendData, nil
@@ -331,8 +353,13 @@ func (wx *Wechat) SendAll(Tagid int, Msgtype, Content string) (sendData ResMsg,
 
 //SendList https://api.weixin.qq.com/cgi-bin/message/mass/send?access_token=ACCESS_TOKEN
 func (wx *Wechat) SendList(userList []string, Msgtype, Content string) (sendData ResMsg, err error) {
+	return wx.SendListContext(context.Background(), userList, Msgtype, Content)
+}
+
+//SendListContext 同SendList，使用ctx控制请求的取消和超时
+func (wx *Wechat) SendListContext(ctx context.Context, userList []string, Msgtype, Content string) (sendData ResMsg, err error) {
 	param := make(map[string]string)
-	param["access_token"] = wx.AccessToken
+	param["access_token"] = ""
 
 	var data interface{}
 	switch Msgtype {
@@ -381,21 +408,17 @@ func (wx *Wechat) SendList(userList []string, Msgtype, Content string) (sendData
 	}
 
 	d, _ := json.Marshal(data)
-	log.Println(string(d))
-	req, err := http.NewRequest("POST", common.Param("https://api.weixin.qq.com/cgi-bin/message/mass/send", param),
+	req, err := http.NewRequestWithContext(ctx, "POST", common.Param("https://api.weixin.qq.com/cgi-bin/message/mass/send", param),
 		bytes.NewReader(d))
 	if err != nil {
-		log.Println(err)
 		return sendData, err
 	}
-	resp, err := common.RequsetJSON(req, 0)
+	resp, err := wx.RequsetJSON(req, 0)
 	if err != nil {
-		log.Println(err)
 		return sendData, err
 	}
 	err = json.Unmarshal(resp, &sendData)
 	if err != nil {
-		log.Println(err)
 		return sendData, err
 	}
 	return sendData, nil
@@ -403,19 +426,24 @@ func (wx *Wechat) SendList(userList []string, Msgtype, Content string) (sendData
 
 //DeleteMsg https://api.weixin.qq.com/cgi-bin/message/mass/delete?access_token=ACCESS_TOKEN
 func (wx *Wechat) DeleteMsg(msgid string) int {
+	return wx.DeleteMsgContext(context.Background(), msgid)
+}
+
+//DeleteMsgContext 同DeleteMsg，使用ctx控制请求的取消和超时
+func (wx *Wechat) DeleteMsgContext(ctx context.Context, msgid string) int {
 	param := make(map[string]string)
-	param["access_token"] = wx.AccessToken
+	param["access_token"] = ""
 	t := STMediaid{msgid}
 	d, _ := json.Marshal(t)
-	req, err := http.NewRequest("POST", common.Param("https://api.weixin.qq.com/cgi-bin/message/mass/delete", param),
+	req, err := http.NewRequestWithContext(ctx, "POST", common.Param("https://api.weixin.qq.com/cgi-bin/message/mass/delete", param),
 		bytes.NewReader(d))
 	if err != nil {
-		log.Println(err)
+		wx.log(common.LevelError, "DeleteMsg failed", common.F("error", err))
 		return 0
 	}
-	_, err = common.RequsetJSON(req, 0)
+	_, err = wx.RequsetJSON(req, 0)
 	if err != nil {
-		log.Println(err)
+		wx.log(common.LevelError, "DeleteMsg failed", common.F("error", err))
 		return 0
 	}
 	return 1
@@ -423,8 +451,13 @@ func (wx *Wechat) DeleteMsg(msgid string) int {
 
 //PreviewMsg https://api.weixin.qq.com/cgi-bin/message/mass/preview?access_token=ACCESS_TOKEN
 func (wx *Wechat) PreviewMsg(openid, wxname, Msgtype, Content string) int {
+	return wx.PreviewMsgContext(context.Background(), openid, wxname, Msgtype, Content)
+}
+
+//PreviewMsgContext 同PreviewMsg，使用ctx控制请求的取消和超时
+func (wx *Wechat) PreviewMsgContext(ctx context.Context, openid, wxname, Msgtype, Content string) int {
 	param := make(map[string]string)
-	param["access_token"] = wx.AccessToken
+	param["access_token"] = ""
 
 	var data interface{}
 	switch Msgtype {
@@ -478,15 +511,15 @@ func (wx *Wechat) PreviewMsg(openid, wxname, Msgtype, Content string) int {
 	}
 
 	d, _ := json.Marshal(data)
-	req, err := http.NewRequest("POST", common.Param("https://api.weixin.qq.com/cgi-bin/message/mass/preview", param),
+	req, err := http.NewRequestWithContext(ctx, "POST", common.Param("https://api.weixin.qq.com/cgi-bin/message/mass/preview", param),
 		bytes.NewReader(d))
 	if err != nil {
-		log.Println(err)
+		wx.log(common.LevelError, "PreviewMsg failed", common.F("error", err))
 		return 0
 	}
-	_, err = common.RequsetJSON(req, 0)
+	_, err = wx.RequsetJSON(req, 0)
 	if err != nil {
-		log.Println(err)
+		wx.log(common.LevelError, "PreviewMsg failed", common.F("error", err))
 		return 0
 	}
 	return 1
@@ -494,20 +527,25 @@ func (wx *Wechat) PreviewMsg(openid, wxname, Msgtype, Content string) int {
 
 //SendMsg https://api.weixin.qq.com/cgi-bin/message/custom/send?access_token=ACCESS_TOKEN
 func (wx *Wechat) SendMsg(data interface{}) int {
+	return wx.SendMsgContext(context.Background(), data)
+}
+
+//SendMsgContext 同SendMsg，使用ctx控制请求的取消和超时
+func (wx *Wechat) SendMsgContext(ctx context.Context, data interface{}) int {
 	param := make(map[string]string)
-	param["access_token"] = wx.AccessToken
+	param["access_token"] = ""
 
 	d, _ := json.Marshal(data)
-	req, err := http.NewRequest("POST",
+	req, err := http.NewRequestWithContext(ctx, "POST",
 		common.Param("https://api.weixin.qq.com/cgi-bin/message/custom/send", param),
 		bytes.NewReader(d))
 	if err != nil {
-		log.Println(err)
+		wx.log(common.LevelError, "SendMsg failed", common.F("error", err))
 		return 0
 	}
-	_, err = common.RequsetJSON(req, 0)
+	_, err = wx.RequsetJSON(req, 0)
 	if err != nil {
-		log.Println(err)
+		wx.log(common.LevelError, "SendMsg failed", common.F("error", err))
 		return 0
 	}
 	return 1
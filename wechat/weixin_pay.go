@@ -2,12 +2,12 @@ package wechat
 
 import (
 	"bytes"
+	"context"
 	"crypto/md5"
 	"encoding/base64"
 	"encoding/hex"
 	"encoding/xml"
 	"errors"
-	"log"
 	"net/http"
 	"time"
 
@@ -359,13 +359,18 @@ type CallBackRefund struct {
 
 //UnifiedOrder 支付下单https://api.mch.weixin.qq.com/pay/unifiedorder
 func (wx *Wechat) UnifiedOrder(order ReqUnifiedOrder) (data ResUnifiedOrder, err error) {
+	return wx.UnifiedOrderContext(context.Background(), order)
+}
+
+//UnifiedOrderContext 同UnifiedOrder，使用ctx控制请求的取消和超时
+func (wx *Wechat) UnifiedOrderContext(ctx context.Context, order ReqUnifiedOrder) (data ResUnifiedOrder, err error) {
 	order.Appid = wx.Appid
 	order.Mchid = wx.Mch.MchID
 	order.Sign = common.XMLSignMd5(order, wx.Mch.PayKey)
 	d, _ := xml.MarshalIndent(order, "", "\t")
 	// common.PAYLOG.Info("unifiedorder send ", string(d))
-	req, err := http.NewRequest("POST", URLPAYUNIFIEDORDER, bytes.NewReader(d))
-	resBody, err := common.RequsetXML(req, -1)
+	req, err := http.NewRequestWithContext(ctx, "POST", URLPAYUNIFIEDORDER, bytes.NewReader(d))
+	resBody, err := wx.requsetXML(req)
 	// common.PAYLOG.Info("unifiedorder recv ", string(resBody))
 	if err != nil {
 		return data, err
@@ -377,7 +382,7 @@ func (wx *Wechat) UnifiedOrder(order ReqUnifiedOrder) (data ResUnifiedOrder, err
 	Sign := data.Sign
 	data.Sign = ""
 	if common.XMLSignMd5(data, wx.Mch.PayKey) != Sign {
-		log.Println(string(resBody), wx.Mch.PayKey)
+		wx.log(common.LevelError, "UnifiedOrder response sign mismatch", common.F("response", resBody))
 		return data, errors.New("签名错误")
 	}
 	return data, nil
@@ -385,6 +390,11 @@ func (wx *Wechat) UnifiedOrder(order ReqUnifiedOrder) (data ResUnifiedOrder, err
 
 //QueryOrder 查询订单https://api.mch.weixin.qq.com/pay/orderquery
 func (wx *Wechat) QueryOrder(transactionid, outTradeNo string) (data ResQueryOrder, err error) {
+	return wx.QueryOrderContext(context.Background(), transactionid, outTradeNo)
+}
+
+//QueryOrderContext 同QueryOrder，使用ctx控制请求的取消和超时
+func (wx *Wechat) QueryOrderContext(ctx context.Context, transactionid, outTradeNo string) (data ResQueryOrder, err error) {
 
 	queryOrder := ReqQueryOrder{
 		Appid:         wx.Appid,
@@ -395,13 +405,12 @@ func (wx *Wechat) QueryOrder(transactionid, outTradeNo string) (data ResQueryOrd
 
 	queryOrder.Sign = common.XMLSignMd5(queryOrder, wx.Mch.PayKey)
 	d, _ := xml.MarshalIndent(queryOrder, "", "\t")
-	req, err := http.NewRequest("POST", URLPAYORDERQUERY, bytes.NewReader(d))
+	req, err := http.NewRequestWithContext(ctx, "POST", URLPAYORDERQUERY, bytes.NewReader(d))
 	if err != nil {
 		return data, err
 	}
-	resBody, err := common.RequsetXML(req, -1)
+	resBody, err := wx.requsetXML(req)
 	if err != nil {
-		log.Println(err)
 		return data, err
 	}
 	err = xml.Unmarshal(resBody, &data)
@@ -411,7 +420,7 @@ func (wx *Wechat) QueryOrder(transactionid, outTradeNo string) (data ResQueryOrd
 	Sign := data.Sign
 	data.Sign = ""
 	if common.XMLSignMd5(data, wx.Mch.PayKey) != Sign {
-		log.Println(string(resBody), wx.Mch.PayKey)
+		wx.log(common.LevelError, "QueryOrder response sign mismatch", common.F("response", resBody))
 		return data, errors.New("签名错误")
 	}
 	return data, nil
@@ -419,6 +428,11 @@ func (wx *Wechat) QueryOrder(transactionid, outTradeNo string) (data ResQueryOrd
 
 //CloseOrder 关闭订单https://api.mch.weixin.qq.com/pay/closeorder
 func (wx *Wechat) CloseOrder(outTradeNo string) (data ResCloseOrder, err error) {
+	return wx.CloseOrderContext(context.Background(), outTradeNo)
+}
+
+//CloseOrderContext 同CloseOrder，使用ctx控制请求的取消和超时
+func (wx *Wechat) CloseOrderContext(ctx context.Context, outTradeNo string) (data ResCloseOrder, err error) {
 	queryOrder := ReqQueryOrder{
 		Appid:      wx.Appid,
 		Mchid:      wx.Mch.MchID,
@@ -426,13 +440,12 @@ func (wx *Wechat) CloseOrder(outTradeNo string) (data ResCloseOrder, err error)
 		Noncestr:   common.RandomStr(20, 3)}
 	queryOrder.Sign = common.XMLSignMd5(queryOrder, wx.Mch.PayKey)
 	d, _ := xml.MarshalIndent(queryOrder, "", "\t")
-	req, err := http.NewRequest("POST", URLPAYCLOSEORDER, bytes.NewReader(d))
+	req, err := http.NewRequestWithContext(ctx, "POST", URLPAYCLOSEORDER, bytes.NewReader(d))
 	if err != nil {
 		return data, err
 	}
-	resBody, err := common.RequsetXML(req, -1)
+	resBody, err := wx.requsetXML(req)
 	if err != nil {
-		log.Println(err)
 		return data, err
 	}
 	err = xml.Unmarshal(resBody, &data)
@@ -442,7 +455,7 @@ func (wx *Wechat) CloseOrder(outTradeNo string) (data ResCloseOrder, err error)
 	Sign := data.Sign
 	data.Sign = ""
 	if common.XMLSignMd5(data, wx.Mch.PayKey) != Sign {
-		log.Println(string(resBody), wx.Mch.PayKey)
+		wx.log(common.LevelError, "CloseOrder response sign mismatch", common.F("response", resBody))
 		return data, errors.New("签名错误")
 	}
 	return data, nil
@@ -450,6 +463,11 @@ func (wx *Wechat) CloseOrder(outTradeNo string) (data ResCloseOrder, err error)
 
 //Refund 申请退款https://api.mch.weixin.qq.com/secapi/pay/refund
 func (wx *Wechat) Refund(refund ReqRefund) (data ResRefund, err error) {
+	return wx.RefundContext(context.Background(), refund)
+}
+
+//RefundContext 同Refund，使用ctx控制请求的取消和超时
+func (wx *Wechat) RefundContext(ctx context.Context, refund ReqRefund) (data ResRefund, err error) {
 	refund.Appid = wx.Appid
 	refund.Mchid = wx.Mch.MchID
 	refund.Sign = common.XMLSignMd5(refund, wx.Mch.PayKey)
@@ -457,7 +475,7 @@ func (wx *Wechat) Refund(refund ReqRefund) (data ResRefund, err error) {
 	if err != nil {
 		return data, err
 	}
-	req, err := http.NewRequest("POST", URLPAYREFUND, bytes.NewReader(d))
+	req, err := http.NewRequestWithContext(ctx, "POST", URLPAYREFUND, bytes.NewReader(d))
 	if err != nil {
 		return data, err
 	}
@@ -475,7 +493,7 @@ func (wx *Wechat) Refund(refund ReqRefund) (data ResRefund, err error) {
 	Sign := data.Sign
 	data.Sign = ""
 	if common.XMLSignMd5(data, wx.Mch.PayKey) != Sign {
-		log.Println(string(resBody), wx.Mch.PayKey)
+		wx.log(common.LevelError, "Refund response sign mismatch", common.F("response", resBody))
 		return data, errors.New("签名错误")
 	}
 	return data, nil
@@ -483,6 +501,11 @@ func (wx *Wechat) Refund(refund ReqRefund) (data ResRefund, err error) {
 
 //RefundQuery 申请退款查询https://api.mch.weixin.qq.com/pay/refundquery
 func (wx *Wechat) RefundQuery(refund ReqRefundquery) (data ResReqRefundquery, err error) {
+	return wx.RefundQueryContext(context.Background(), refund)
+}
+
+//RefundQueryContext 同RefundQuery，使用ctx控制请求的取消和超时
+func (wx *Wechat) RefundQueryContext(ctx context.Context, refund ReqRefundquery) (data ResReqRefundquery, err error) {
 	refund.Appid = wx.Appid
 	refund.Mchid = wx.Mch.MchID
 	refund.Sign = common.XMLSignMd5(refund, wx.Mch.PayKey)
@@ -490,11 +513,11 @@ func (wx *Wechat) RefundQuery(refund ReqRefundquery) (data ResReqRefundquery, er
 	if err != nil {
 		return data, err
 	}
-	req, err := http.NewRequest("POST", URLPAYREFUNDQUERY, bytes.NewReader(d))
+	req, err := http.NewRequestWithContext(ctx, "POST", URLPAYREFUNDQUERY, bytes.NewReader(d))
 	if err != nil {
 		return data, err
 	}
-	resBody, err := common.RequsetXML(req, -1)
+	resBody, err := wx.requsetXML(req)
 	if err != nil {
 		return data, err
 	}
@@ -505,7 +528,7 @@ func (wx *Wechat) RefundQuery(refund ReqRefundquery) (data ResReqRefundquery, er
 	Sign := data.Sign
 	data.Sign = ""
 	if common.XMLSignMd5(data, wx.Mch.PayKey) != Sign {
-		log.Println(string(resBody), wx.Mch.PayKey)
+		wx.log(common.LevelError, "RefundQuery response sign mismatch", common.F("response", resBody))
 		return data, errors.New("签名错误")
 	}
 	return data, nil
@@ -513,6 +536,11 @@ func (wx *Wechat) RefundQuery(refund ReqRefundquery) (data ResReqRefundquery, er
 
 //Downloadbill 下载对账单https://api.mch.weixin.qq.com/pay/downloadbill
 func (wx *Wechat) Downloadbill(billDate string, billType string) (data string, err error) {
+	return wx.DownloadbillContext(context.Background(), billDate, billType)
+}
+
+//DownloadbillContext 同Downloadbill，使用ctx控制请求的取消和超时
+func (wx *Wechat) DownloadbillContext(ctx context.Context, billDate string, billType string) (data string, err error) {
 	queryBill := ReqDownloadBill{
 		Appid:    wx.Appid,
 		Mchid:    wx.Mch.MchID,
@@ -521,33 +549,33 @@ func (wx *Wechat) Downloadbill(billDate string, billType string) (data string, e
 		Noncestr: common.RandomStr(20, 3)}
 	queryBill.Sign = common.XMLSignMd5(queryBill, wx.Mch.PayKey)
 	d, _ := xml.MarshalIndent(queryBill, "", "\t")
-	req, err := http.NewRequest("POST", URLDOWNLOADBILL, bytes.NewReader(d))
+	req, err := http.NewRequestWithContext(ctx, "POST", URLDOWNLOADBILL, bytes.NewReader(d))
 	if err != nil {
 		return data, err
 	}
-	resBody, err := common.RequsetXML(req, -1, false)
+	resBody, err := wx.requsetXML(req, false)
 	if err != nil {
-		log.Println(err)
 		return "", err
 	}
-	// log.Println(string(resBody))
 	return string(resBody), nil
 }
 
 //SendHongbao 发送红包
 func (wx *Wechat) SendHongbao(hb ReqHongbao) (resp ResHongbao, err error) {
+	return wx.SendHongbaoContext(context.Background(), hb)
+}
+
+//SendHongbaoContext 同SendHongbao，使用ctx控制请求的取消和超时
+func (wx *Wechat) SendHongbaoContext(ctx context.Context, hb ReqHongbao) (resp ResHongbao, err error) {
 	hb.Sign = common.XMLSignMd5(hb, wx.Mch.PayKey)
 	data, err := xml.MarshalIndent(&hb, "", " ")
 	if err != nil {
 		return resp, err
 	}
-	res, err := wx.httpsPost("https://api.mch.weixin.qq.com/mmpaymkttransfers/sendredpack", data, "text/xml")
+	resBody, err := wx.httpsPost(ctx, "https://api.mch.weixin.qq.com/mmpaymkttransfers/sendredpack", data, "text/xml")
 	if err != nil {
-		log.Println(res, err)
 		return resp, err
 	}
-	resBody := make([]byte, 1024)
-	// res.Body.Read(resBody)
 	err = xml.Unmarshal(resBody, &resp)
 	if err != nil {
 		return resp, err
@@ -1,28 +1,135 @@
-package wechat
+package wechat_test
 
-import "time"
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
 
-const (
-	Appid            = "wxc161f1d6315f3530"
-	Appsecret        = "206acd0c5422624301be1b06e1d407e8"
-	Token            = "bjwonder"
-	Encodingaeskey   = "g6Lyi5o09BUJFdJvsUvFKX6ZEXkSC71gwWQqEKgIRDc"
-	AccessToken      = ""
-	AuthorizedDomain = ""
+	"github.com/wei193/component/common"
+	"github.com/wei193/component/wechat"
+	"github.com/wei193/component/wechattest"
 )
 
-var testWx *Wechat
+func newTestWechat(t *testing.T) (*wechattest.Server, *wechat.Wechat) {
+	srv := wechattest.NewServer()
+	wx := srv.Wechat(wechattest.Appid, wechattest.Appsecret)
+	if err := wx.GetAccessToken(); err != nil {
+		srv.Close()
+		t.Fatal(err)
+	}
+	return srv, wx
+}
 
-func init() {
-	testWx = New(Appid, Appsecret, Token, Encodingaeskey, AuthorizedDomain)
-	if testWx.AccessToken == "" {
-		err := testWx.GetAccessToken()
-		if err != nil {
-			panic(err)
-		}
-	} else {
-		testWx.AccessToken = ""
-		testWx.AccessTokenExpires = time.Now().Add(2 * time.Hour).Unix()
+func TestAccessTokenExpired(t *testing.T) {
+	srv, wx := newTestWechat(t)
+	defer srv.Close()
+
+	srv.ExpireTokens()
+	if _, err := wx.GetMenu(); err != nil {
+		t.Fatal(err)
+	}
+	if n := srv.Calls("/cgi-bin/token"); n != 2 {
+		t.Errorf("token requests = %d, want 2", n)
 	}
 
+	srv.Inject("/cgi-bin/menu/get", wechattest.Fault{Errcode: 45009, Errmsg: "reach max api daily quota limit"})
+	if _, err := wx.GetMenu(); !common.IsRateLimited(err) {
+		t.Errorf("err = %v, want rate limited", err)
+	}
+}
+
+func TestCheckAccessToken(t *testing.T) {
+	srv, wx := newTestWechat(t)
+	defer srv.Close()
+
+	if err := wx.CheckAccessToken(); err != nil {
+		t.Fatal(err)
+	}
+	//令牌失效时报告错误，不自动刷新
+	srv.ExpireTokens()
+	if err := wx.CheckAccessToken(); !common.IsTokenExpired(err) {
+		t.Errorf("err = %v, want token expired", err)
+	}
+	if n := srv.Calls("/cgi-bin/token"); n != 1 {
+		t.Errorf("token requests = %d, want 1", n)
+	}
+}
+
+func TestAccessTokenFromStore(t *testing.T) {
+	srv := wechattest.NewServer()
+	defer srv.Close()
+	store := common.NewMemoryStore()
+	if err := store.SetToken(wechattest.Appid, common.TokenAccessToken, srv.IssueToken(), time.Now().Unix()+3600); err != nil {
+		t.Fatal(err)
+	}
+
+	//重启后内存中没有令牌，从令牌存储读取
+	wx := srv.Wechat(wechattest.Appid, wechattest.Appsecret)
+	wx.Store = store
+	if _, err := wx.GetMenu(); err != nil {
+		t.Fatal(err)
+	}
+	if n := srv.Calls("/cgi-bin/token"); n != 0 {
+		t.Errorf("token requests = %d, want 0", n)
+	}
+}
+
+func TestUsers(t *testing.T) {
+	srv, wx := newTestWechat(t)
+	defer srv.Close()
+
+	users, err := wx.GetAllUserInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 2 || users[1].Openid != "OPENID2" {
+		t.Errorf("users = %+v", users)
+	}
+}
+
+func TestPay(t *testing.T) {
+	srv, wx := newTestWechat(t)
+	defer srv.Close()
+
+	order, err := wx.UnifiedOrder(wechat.ReqUnifiedOrder{
+		NonceStr: "nonce", Body: "test", OutTradeNo: "T001", TotalFee: 1,
+		SpbillCreateIP: "127.0.0.1", NotifyURL: "https://example.com/notify", TradeType: "JSAPI"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.Prepayid != "wx_prepay_T001" {
+		t.Errorf("prepay_id = %s", order.Prepayid)
+	}
+	if _, err = wx.QueryOrder("", "T001"); err != nil {
+		t.Error(err)
+	}
+
+	srv.Inject("/pay/closeorder", wechattest.Fault{Errmsg: "签名错误"})
+	if _, err = wx.CloseOrder("T001"); err == nil {
+		t.Error("CloseOrder with injected fault succeeded")
+	}
+}
+
+func TestContextCancel(t *testing.T) {
+	srv, wx := newTestWechat(t)
+	defer srv.Close()
+
+	release := make(chan struct{})
+	defer close(release)
+	srv.Handle("/cgi-bin/menu/get", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := wx.GetMenuContext(ctx); err == nil {
+		t.Fatal("GetMenuContext succeeded after deadline")
+	}
+	if d := time.Since(start); d > 2*time.Second {
+		t.Errorf("GetMenuContext returned after %s", d)
+	}
 }
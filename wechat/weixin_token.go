@@ -0,0 +1,164 @@
+package wechat
+
+import (
+	"context"
+	"time"
+
+	"github.com/wei193/component/common"
+)
+
+//TokenFunc 获取新的access_token，返回令牌及有效秒数
+//未设置时使用appsecret调用cgi-bin/token获取，ctx为触发刷新的请求的context
+type TokenFunc func(ctx context.Context) (token string, expiresIn int, err error)
+
+//ValidAccessToken 获取有效的access_token
+//依次读取内存、令牌存储，均已过期时获取刷新锁并重新获取，写入令牌存储
+func (wx *Wechat) ValidAccessToken() (string, error) {
+	return wx.ValidAccessTokenContext(context.Background())
+}
+
+//ValidAccessTokenContext 同ValidAccessToken，使用ctx控制请求的取消和超时
+func (wx *Wechat) ValidAccessTokenContext(ctx context.Context) (string, error) {
+	wx.tokenLock.Lock()
+	defer wx.tokenLock.Unlock()
+	if common.TokenValid(wx.AccessToken, wx.AccessTokenExpires) {
+		return wx.AccessToken, nil
+	}
+	unlock, err := common.LockToken(wx.Locker, wx.Appid, common.TokenAccessToken)
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+
+	token, expires, err := wx.loadToken(common.TokenAccessToken)
+	if err != nil {
+		return "", err
+	}
+	if common.TokenValid(token, expires) {
+		wx.AccessToken, wx.AccessTokenExpires = token, expires
+		return token, nil
+	}
+	return wx.refreshAccessToken(ctx)
+}
+
+//RefreshAccessToken 强制刷新access_token
+//获取刷新锁后若令牌存储中已有其他实例刷新的令牌则直接使用
+func (wx *Wechat) RefreshAccessToken() (string, error) {
+	return wx.RefreshAccessTokenContext(context.Background())
+}
+
+//RefreshAccessTokenContext 同RefreshAccessToken，使用ctx控制请求的取消和超时
+func (wx *Wechat) RefreshAccessTokenContext(ctx context.Context) (string, error) {
+	wx.tokenLock.Lock()
+	defer wx.tokenLock.Unlock()
+	unlock, err := common.LockToken(wx.Locker, wx.Appid, common.TokenAccessToken)
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+
+	token, expires, err := wx.loadToken(common.TokenAccessToken)
+	if err != nil {
+		return "", err
+	}
+	if common.TokenValid(token, expires) && token != wx.AccessToken {
+		wx.AccessToken, wx.AccessTokenExpires = token, expires
+		return token, nil
+	}
+	return wx.refreshAccessToken(ctx)
+}
+
+//refreshAccessToken 调用TokenFunc获取新令牌，需持有tokenLock及刷新锁
+func (wx *Wechat) refreshAccessToken(ctx context.Context) (string, error) {
+	fn := wx.TokenFunc
+	if fn == nil {
+		fn = wx.requsetAccessToken
+	}
+	token, expiresIn, err := fn(ctx)
+	if err != nil {
+		return "", err
+	}
+	err = wx.setAccessToken(token, time.Now().Unix()+int64(expiresIn))
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+//CurrentAccessToken 当前内存中的access_token及过期时间，不检查有效性也不刷新
+func (wx *Wechat) CurrentAccessToken() (token string, expires int64) {
+	wx.tokenLock.Lock()
+	defer wx.tokenLock.Unlock()
+	return wx.AccessToken, wx.AccessTokenExpires
+}
+
+//SetAccessToken 设置access_token并写入令牌存储
+func (wx *Wechat) SetAccessToken(token string, expires int64) error {
+	wx.tokenLock.Lock()
+	defer wx.tokenLock.Unlock()
+	return wx.setAccessToken(token, expires)
+}
+
+func (wx *Wechat) setAccessToken(token string, expires int64) error {
+	wx.AccessToken = token
+	wx.AccessTokenExpires = expires
+	return wx.saveToken(common.TokenAccessToken, token, expires)
+}
+
+//ValidJsapiTicket 获取有效的jsapi_ticket
+func (wx *Wechat) ValidJsapiTicket() (string, error) {
+	return wx.ValidJsapiTicketContext(context.Background())
+}
+
+//ValidJsapiTicketContext 同ValidJsapiTicket，使用ctx控制请求的取消和超时
+func (wx *Wechat) ValidJsapiTicketContext(ctx context.Context) (string, error) {
+	wx.ticketLock.Lock()
+	defer wx.ticketLock.Unlock()
+	if common.TokenValid(wx.JsapiTicket, wx.JsapiTokenExpires) {
+		return wx.JsapiTicket, nil
+	}
+	unlock, err := common.LockToken(wx.Locker, wx.Appid, common.TokenJsapiTicket)
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+
+	ticket, expires, err := wx.loadToken(common.TokenJsapiTicket)
+	if err != nil {
+		return "", err
+	}
+	if common.TokenValid(ticket, expires) {
+		wx.JsapiTicket, wx.JsapiTokenExpires = ticket, expires
+		return ticket, nil
+	}
+
+	_, err = wx.ValidAccessTokenContext(ctx)
+	if err != nil {
+		return "", err
+	}
+	err = wx.refreshJsapiTicket(ctx)
+	if err != nil {
+		return "", err
+	}
+	return wx.JsapiTicket, nil
+}
+
+//loadToken 从令牌存储读取，未设置存储或不存在时返回空
+func (wx *Wechat) loadToken(kind common.TokenKind) (string, int64, error) {
+	if wx.Store == nil {
+		return "", 0, nil
+	}
+	token, expires, err := wx.Store.GetToken(wx.Appid, kind)
+	if err == common.ErrTokenNotFound {
+		return "", 0, nil
+	}
+	return token, expires, err
+}
+
+//saveToken 写入令牌存储
+func (wx *Wechat) saveToken(kind common.TokenKind, token string, expires int64) error {
+	if wx.Store == nil {
+		return nil
+	}
+	return wx.Store.SetToken(wx.Appid, kind, token, expires)
+}
@@ -6,11 +6,10 @@ package wechat
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"net/http"
-
-	"github.com/wei193/component/common"
 )
 
 //设置所属行业https://api.weixin.qq.com/cgi-bin/template/api_set_industry?access_token=ACCESS_TOKEN
@@ -41,16 +40,20 @@ type Template struct {
 
 //SendTemplate 发送模板消息https://api.weixin.qq.com/cgi-bin/message/template/send?access_token=ACCESS_TOKEN
 func (wx *Wechat) SendTemplate(data string) (string, error) {
+	return wx.SendTemplateContext(context.Background(), data)
+}
+
+//SendTemplateContext 同SendTemplate，使用ctx控制请求的取消和超时
+func (wx *Wechat) SendTemplateContext(ctx context.Context, data string) (string, error) {
 	buf, err := base64.StdEncoding.DecodeString(data)
 	if err != nil {
 		return "", err
 	}
-	req, err := http.NewRequest("POST", TEMPLATESENDURL+"?access_token="+
-		wx.AccessToken, bytes.NewReader(buf))
+	req, err := http.NewRequestWithContext(ctx, "POST", TEMPLATESENDURL+"?access_token=", bytes.NewReader(buf))
 	if err != nil {
 		return "", err
 	}
-	res, err := common.RequsetJSON(req, 0)
+	res, err := wx.RequsetJSON(req, 0)
 	if err != nil {
 		return "", err
 	}
@@ -59,6 +62,12 @@ func (wx *Wechat) SendTemplate(data string) (string, error) {
 
 //SendTemplateToUser 发送模板消息到用户
 func (wx *Wechat) SendTemplateToUser(touser, templateid, url string,
+	data map[string]TemplateData) (string, error) {
+	return wx.SendTemplateToUserContext(context.Background(), touser, templateid, url, data)
+}
+
+//SendTemplateToUserContext 同SendTemplateToUser，使用ctx控制请求的取消和超时
+func (wx *Wechat) SendTemplateToUserContext(ctx context.Context, touser, templateid, url string,
 	data map[string]TemplateData) (string, error) {
 	tpl := Template{
 		Touser:     touser,
@@ -67,12 +76,11 @@ func (wx *Wechat) SendTemplateToUser(touser, templateid, url string,
 		Data:       data,
 	}
 	rdata, _ := json.Marshal(tpl)
-	req, err := http.NewRequest("POST", TEMPLATESENDURL+"?access_token="+
-		wx.AccessToken, bytes.NewReader(rdata))
+	req, err := http.NewRequestWithContext(ctx, "POST", TEMPLATESENDURL+"?access_token=", bytes.NewReader(rdata))
 	if err != nil {
 		return "", err
 	}
-	res, err := common.RequsetJSON(req, 0)
+	res, err := wx.RequsetJSON(req, 0)
 	if err != nil {
 		return "", err
 	}
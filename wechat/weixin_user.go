@@ -2,9 +2,9 @@ package wechat
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
-	"log"
 	"net/http"
 	"strings"
 
@@ -87,19 +87,22 @@ type STUserList struct {
 
 //GetUserInfoToken GetUserInfo 获取用户信息by user_token
 func GetUserInfoToken(accessToken, openid string) (userInfo STUserInfo, err error) {
+	return GetUserInfoTokenContext(context.Background(), accessToken, openid)
+}
+
+//GetUserInfoTokenContext 同GetUserInfoToken，使用ctx控制请求的取消和超时
+func GetUserInfoTokenContext(ctx context.Context, accessToken, openid string) (userInfo STUserInfo, err error) {
 	param := make(map[string]string)
 	param["access_token"] = accessToken
 	param["openid"] = openid
 
-	req, err := http.NewRequest("GET", common.Param("https://api.weixin.qq.com/sns/userinfo?lang=zh_CN", param), nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", common.Param("https://api.weixin.qq.com/sns/userinfo?lang=zh_CN", param), nil)
 	resBody, err := common.RequsetJSON(req, TOKENIGNORE)
 	if err != nil {
-		log.Println(err)
 		return userInfo, err
 	}
 	err = json.Unmarshal(resBody, &userInfo)
 	if err != nil {
-		log.Println(err)
 		return userInfo, err
 	}
 	return userInfo, nil
@@ -107,24 +110,27 @@ func GetUserInfoToken(accessToken, openid string) (userInfo STUserInfo, err erro
 
 //GetUserToken 获取用户user_token
 func (wx *Wechat) GetUserToken(Code string) (uToken ResUserToken, err error) {
+	return wx.GetUserTokenContext(context.Background(), Code)
+}
+
+//GetUserTokenContext 同GetUserToken，使用ctx控制请求的取消和超时
+func (wx *Wechat) GetUserTokenContext(ctx context.Context, Code string) (uToken ResUserToken, err error) {
 
 	param := make(map[string]string)
 	param["appid"] = wx.Appid
 	param["secret"] = wx.Appsecret
 	param["code"] = Code
 	param["grant_type"] = "authorization_code"
-	req, err := http.NewRequest("GET", common.Param("https://api.weixin.qq.com/sns/oauth2/access_token", param), nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", common.Param("https://api.weixin.qq.com/sns/oauth2/access_token", param), nil)
 	if err != nil {
 		return uToken, err
 	}
-	resBody, err := common.RequsetJSON(req, 1)
+	resBody, err := wx.RequsetJSON(req, 1)
 	if err != nil {
-		log.Println(err)
 		return uToken, err
 	}
 	err = json.Unmarshal(resBody, &uToken)
 	if err != nil {
-		log.Println(err)
 		return uToken, err
 	}
 	return uToken, nil
@@ -132,19 +138,22 @@ func (wx *Wechat) GetUserToken(Code string) (uToken ResUserToken, err error) {
 
 //GetUserInfoToken GetUserInfo 获取用户信息by user_token
 func (wx *Wechat) GetUserInfoToken(accessToken, openid string) (userInfo STUserInfo, err error) {
+	return wx.GetUserInfoTokenContext(context.Background(), accessToken, openid)
+}
+
+//GetUserInfoTokenContext 同GetUserInfoToken，使用ctx控制请求的取消和超时
+func (wx *Wechat) GetUserInfoTokenContext(ctx context.Context, accessToken, openid string) (userInfo STUserInfo, err error) {
 	param := make(map[string]string)
 	param["access_token"] = accessToken
 	param["openid"] = openid
 
-	req, err := http.NewRequest("GET", common.Param("https://api.weixin.qq.com/sns/userinfo?lang=zh_CN", param), nil)
-	resBody, err := common.RequsetJSON(req, TOKENIGNORE)
+	req, err := http.NewRequestWithContext(ctx, "GET", common.Param("https://api.weixin.qq.com/sns/userinfo?lang=zh_CN", param), nil)
+	resBody, err := wx.RequsetJSON(req, TOKENIGNORE)
 	if err != nil {
-		log.Println(err)
 		return userInfo, err
 	}
 	err = json.Unmarshal(resBody, &userInfo)
 	if err != nil {
-		log.Println(err)
 		return userInfo, err
 	}
 	return userInfo, nil
@@ -152,12 +161,17 @@ func (wx *Wechat) GetUserInfoToken(accessToken, openid string) (userInfo STUserI
 
 //GetUserInfo 获取用户信息by ac_token
 func (wx *Wechat) GetUserInfo(openid string) (userInfo STUserInfo, err error) {
+	return wx.GetUserInfoContext(context.Background(), openid)
+}
+
+//GetUserInfoContext 同GetUserInfo，使用ctx控制请求的取消和超时
+func (wx *Wechat) GetUserInfoContext(ctx context.Context, openid string) (userInfo STUserInfo, err error) {
 
 	param := make(map[string]string)
-	param["access_token"] = wx.AccessToken
+	param["access_token"] = ""
 	param["openid"] = openid
-	req, err := http.NewRequest("GET", common.Param("https://api.weixin.qq.com/cgi-bin/user/info?lang=zh_CN", param), nil)
-	resBody, err := common.RequsetJSON(req, 0)
+	req, err := http.NewRequestWithContext(ctx, "GET", common.Param("https://api.weixin.qq.com/cgi-bin/user/info?lang=zh_CN", param), nil)
+	resBody, err := wx.RequsetJSON(req, 0)
 	if err != nil {
 		return userInfo, err
 	}
@@ -171,10 +185,15 @@ func (wx *Wechat) GetUserInfo(openid string) (userInfo STUserInfo, err error) {
 
 //GetUsers 批量获取用户信息
 func (wx *Wechat) GetUsers(openidList []string) (userInfo []STUserInfo, err error) {
+	return wx.GetUsersContext(context.Background(), openidList)
+}
+
+//GetUsersContext 同GetUsers，使用ctx控制请求的取消和超时
+func (wx *Wechat) GetUsersContext(ctx context.Context, openidList []string) (userInfo []STUserInfo, err error) {
 	var openids []STOpenid
 	for index, openid := range openidList {
 		if (index+1)%99 == 0 {
-			user, err := wx.GetUsers100(openids)
+			user, err := wx.GetUsers100Context(ctx, openids)
 			if err != nil {
 				return user, err
 			}
@@ -183,7 +202,7 @@ func (wx *Wechat) GetUsers(openidList []string) (userInfo []STUserInfo, err erro
 		}
 		openids = append(openids, STOpenid{openid})
 	}
-	user, err := wx.GetUsers100(openids)
+	user, err := wx.GetUsers100Context(ctx, openids)
 	if err != nil {
 		return user, err
 	}
@@ -193,8 +212,12 @@ func (wx *Wechat) GetUsers(openidList []string) (userInfo []STUserInfo, err erro
 
 //GetUsers100 GetUsers100
 func (wx *Wechat) GetUsers100(openids []STOpenid) (userInfo []STUserInfo, err error) {
+	return wx.GetUsers100Context(context.Background(), openids)
+}
+
+//GetUsers100Context 同GetUsers100，使用ctx控制请求的取消和超时
+func (wx *Wechat) GetUsers100Context(ctx context.Context, openids []STOpenid) (userInfo []STUserInfo, err error) {
 	if len(openids) > 100 {
-		log.Println("数据太多")
 		return nil, errors.New("数据太多")
 	}
 	type stList struct {
@@ -203,10 +226,9 @@ func (wx *Wechat) GetUsers100(openids []STOpenid) (userInfo []STUserInfo, err er
 	t := stList{openids}
 	d, err := json.Marshal(t)
 
-	req, err := http.NewRequest("POST", "https://api.weixin.qq.com/cgi-bin/user/info/batchget?access_token="+wx.AccessToken, bytes.NewReader(d))
-	resBody, err := common.RequsetJSON(req, 0)
+	req, err := http.NewRequestWithContext(ctx, "POST", "https://api.weixin.qq.com/cgi-bin/user/info/batchget?access_token=", bytes.NewReader(d))
+	resBody, err := wx.RequsetJSON(req, 0)
 	if err != nil {
-		log.Println(err)
 		return userInfo, err
 	}
 
@@ -218,7 +240,7 @@ func (wx *Wechat) GetUsers100(openids []STOpenid) (userInfo []STUserInfo, err er
 	tempData = strings.Replace(tempData, "\x0e", "", -1)
 	err = json.Unmarshal([]byte(tempData), &userList)
 	if err != nil {
-		log.Println(err, string(resBody))
+		wx.log(common.LevelError, "GetUsers100 failed", common.F("error", err), common.F("response", resBody))
 		//		return userInfo, err
 	}
 	return userList.UserInfoList, nil
@@ -226,22 +248,27 @@ func (wx *Wechat) GetUsers100(openids []STOpenid) (userInfo []STUserInfo, err er
 
 //GetUserList 获取用户openid列表
 func (wx *Wechat) GetUserList() []string {
+	return wx.GetUserListContext(context.Background())
+}
+
+//GetUserListContext 同GetUserList，使用ctx控制请求的取消和超时
+func (wx *Wechat) GetUserListContext(ctx context.Context) []string {
 	var openidList []string
 	nextOpenid := ""
 loop:
 	param := make(map[string]string)
-	param["access_token"] = wx.AccessToken
+	param["access_token"] = ""
 	param["next_openid"] = nextOpenid
-	req, err := http.NewRequest("GET", common.Param("https://api.weixin.qq.com/cgi-bin/user/get", param), nil)
-	resBody, err := common.RequsetJSON(req, 0)
+	req, err := http.NewRequestWithContext(ctx, "GET", common.Param("https://api.weixin.qq.com/cgi-bin/user/get", param), nil)
+	resBody, err := wx.RequsetJSON(req, 0)
 	if err != nil {
-		log.Println(err)
+		wx.log(common.LevelError, "GetUserList failed", common.F("error", err))
 		return openidList
 	}
 	var userList STUserList
 	err = json.Unmarshal(resBody, &userList)
 	if err != nil || userList.Errcode != 0 {
-		log.Println(string(resBody))
+		wx.log(common.LevelError, "GetUserList failed", common.F("error", err), common.F("errcode", userList.Errcode))
 		return openidList
 	}
 	openidList = append(openidList, userList.Data.Openid...)
@@ -254,27 +281,34 @@ loop:
 
 //GetAllUserInfo 获取所有用户的所有信息
 func (wx *Wechat) GetAllUserInfo() (userInfo []STUserInfo, err error) {
-	return wx.GetUsers(wx.GetUserList())
+	return wx.GetAllUserInfoContext(context.Background())
+}
+
+//GetAllUserInfoContext 同GetAllUserInfo，使用ctx控制请求的取消和超时
+func (wx *Wechat) GetAllUserInfoContext(ctx context.Context) (userInfo []STUserInfo, err error) {
+	return wx.GetUsersContext(ctx, wx.GetUserListContext(ctx))
 }
 
 //CreateTag 创建用户标签
 func (wx *Wechat) CreateTag(name string) (tagid int, err error) {
+	return wx.CreateTagContext(context.Background(), name)
+}
+
+//CreateTagContext 同CreateTag，使用ctx控制请求的取消和超时
+func (wx *Wechat) CreateTagContext(ctx context.Context, name string) (tagid int, err error) {
 
 	type tag struct {
 		Tag STTag `json:"tag"`
 	}
 	t := tag{STTag{0, name}}
 	d, _ := json.Marshal(t)
-	log.Println(string(d))
-	req, err := http.NewRequest("POST", "https://api.weixin.qq.com/cgi-bin/tags/create?access_token="+wx.AccessToken, bytes.NewReader(d))
-	resBody, err := common.RequsetJSON(req, 0)
+	req, err := http.NewRequestWithContext(ctx, "POST", "https://api.weixin.qq.com/cgi-bin/tags/create?access_token=", bytes.NewReader(d))
+	resBody, err := wx.RequsetJSON(req, 0)
 	if err != nil {
-		log.Println(err)
 		return 0, err
 	}
 	err = json.Unmarshal(resBody, &t)
 	if err != nil {
-		log.Println(err)
 		return 0, err
 	}
 	return t.Tag.Tagid, nil
@@ -282,19 +316,22 @@ func (wx *Wechat) CreateTag(name string) (tagid int, err error) {
 
 //GetTag 获取所有用户标签
 func (wx *Wechat) GetTag() (data []STTag, err error) {
+	return wx.GetTagContext(context.Background())
+}
+
+//GetTagContext 同GetTag，使用ctx控制请求的取消和超时
+func (wx *Wechat) GetTagContext(ctx context.Context) (data []STTag, err error) {
 	type tags struct {
 		Tags []STTag `json:"tags"`
 	}
-	req, err := http.NewRequest("GET", "https://api.weixin.qq.com/cgi-bin/tags/get?access_token="+wx.AccessToken, nil)
-	resBody, err := common.RequsetJSON(req, 0)
+	req, err := http.NewRequestWithContext(ctx, "GET", "https://api.weixin.qq.com/cgi-bin/tags/get?access_token=", nil)
+	resBody, err := wx.RequsetJSON(req, 0)
 	if err != nil {
-		log.Println(err)
 		return data, err
 	}
 	var tmpData tags
 	err = json.Unmarshal(resBody, &tmpData)
 	if err != nil {
-		log.Println(err)
 		return tmpData.Tags, err
 	}
 	return tmpData.Tags, nil
@@ -302,39 +339,53 @@ func (wx *Wechat) GetTag() (data []STTag, err error) {
 
 //UpdateTag 更新用户标签名
 func (wx *Wechat) UpdateTag(tagid int, name string) (err error) {
+	return wx.UpdateTagContext(context.Background(), tagid, name)
+}
+
+//UpdateTagContext 同UpdateTag，使用ctx控制请求的取消和超时
+func (wx *Wechat) UpdateTagContext(ctx context.Context, tagid int, name string) (err error) {
 	type tags struct {
 		Tag STTag `json:"tag"`
 	}
 	t := tags{STTag{tagid, name}}
 	d, _ := json.Marshal(t)
-	req, err := http.NewRequest("POST", "https://api.weixin.qq.com/cgi-bin/tags/update?access_token="+wx.AccessToken, bytes.NewReader(d))
-	_, err = common.RequsetJSON(req, 0)
+	req, err := http.NewRequestWithContext(ctx, "POST", "https://api.weixin.qq.com/cgi-bin/tags/update?access_token=", bytes.NewReader(d))
+	_, err = wx.RequsetJSON(req, 0)
 	return err
 }
 
 //DelTags 删除用户标签
 func (wx *Wechat) DelTags(tagid int) (err error) {
+	return wx.DelTagsContext(context.Background(), tagid)
+}
+
+//DelTagsContext 同DelTags，使用ctx控制请求的取消和超时
+func (wx *Wechat) DelTagsContext(ctx context.Context, tagid int) (err error) {
 	type stTags struct {
 		Tag STTag `json:"tag"`
 	}
 	t := stTags{STTag{tagid, ""}}
 	d, _ := json.Marshal(t)
-	log.Println(string(d))
-	req, err := http.NewRequest("POST", "https://api.weixin.qq.com/cgi-bin/tags/delete?access_token="+wx.AccessToken, bytes.NewReader(d))
-	_, err = common.RequsetJSON(req, 0)
+	req, err := http.NewRequestWithContext(ctx, "POST", "https://api.weixin.qq.com/cgi-bin/tags/delete?access_token=", bytes.NewReader(d))
+	_, err = wx.RequsetJSON(req, 0)
 	return err
 }
 
 //GetUserTags 获取用户所在标签
 func (wx *Wechat) GetUserTags(openid string) []int {
+	return wx.GetUserTagsContext(context.Background(), openid)
+}
+
+//GetUserTagsContext 同GetUserTags，使用ctx控制请求的取消和超时
+func (wx *Wechat) GetUserTagsContext(ctx context.Context, openid string) []int {
 
 	t := STOpenid{openid}
 	d, _ := json.Marshal(t)
 
-	req, err := http.NewRequest("POST", "https://api.weixin.qq.com/cgi-bin/tags/getidlist?access_token="+wx.AccessToken, bytes.NewReader(d))
-	resBody, err := common.RequsetJSON(req, 0)
+	req, err := http.NewRequestWithContext(ctx, "POST", "https://api.weixin.qq.com/cgi-bin/tags/getidlist?access_token=", bytes.NewReader(d))
+	resBody, err := wx.RequsetJSON(req, 0)
 	if err != nil {
-		log.Println(err)
+		wx.log(common.LevelError, "GetUserTags failed", common.F("error", err))
 		return nil
 	}
 	type stTagid struct {
@@ -343,7 +394,7 @@ func (wx *Wechat) GetUserTags(openid string) []int {
 	var tmpTag stTagid
 	err = json.Unmarshal(resBody, &tmpTag)
 	if err != nil {
-		log.Println(err)
+		wx.log(common.LevelError, "GetUserTags failed", common.F("error", err))
 		return nil
 	}
 	return tmpTag.TagidList
@@ -351,17 +402,21 @@ func (wx *Wechat) GetUserTags(openid string) []int {
 
 //BatchTags 批量为用户打标签
 func (wx *Wechat) BatchTags(openid []string, tagid int) int {
+	return wx.BatchTagsContext(context.Background(), openid, tagid)
+}
+
+//BatchTagsContext 同BatchTags，使用ctx控制请求的取消和超时
+func (wx *Wechat) BatchTagsContext(ctx context.Context, openid []string, tagid int) int {
 	type stOpenid struct {
 		Openid []string `json:"openidList"`
 		Tagid  int      `json:"tagid"`
 	}
 	t := stOpenid{openid, tagid}
 	d, _ := json.Marshal(t)
-	log.Println(string(d))
-	req, err := http.NewRequest("POST", "https://api.weixin.qq.com/cgi-bin/tags/members/batchtagging?access_token="+wx.AccessToken, bytes.NewReader(d))
-	_, err = common.RequsetJSON(req, 0)
+	req, err := http.NewRequestWithContext(ctx, "POST", "https://api.weixin.qq.com/cgi-bin/tags/members/batchtagging?access_token=", bytes.NewReader(d))
+	_, err = wx.RequsetJSON(req, 0)
 	if err != nil {
-		log.Println(err)
+		wx.log(common.LevelError, "BatchTags failed", common.F("error", err))
 		return 0
 	}
 
@@ -370,16 +425,21 @@ func (wx *Wechat) BatchTags(openid []string, tagid int) int {
 
 //UnBatchTags 批量取消用户标签
 func (wx *Wechat) UnBatchTags(openid []string, tagid int) int {
+	return wx.UnBatchTagsContext(context.Background(), openid, tagid)
+}
+
+//UnBatchTagsContext 同UnBatchTags，使用ctx控制请求的取消和超时
+func (wx *Wechat) UnBatchTagsContext(ctx context.Context, openid []string, tagid int) int {
 	type stOpenid struct {
 		Openid []string `json:"openidList"`
 		Tagid  int      `json:"tagid"`
 	}
 	t := stOpenid{openid, tagid}
 	d, _ := json.Marshal(t)
-	req, err := http.NewRequest("POST", "https://api.weixin.qq.com/cgi-bin/tags/members/batchuntagging?access_token="+wx.AccessToken, bytes.NewReader(d))
-	_, err = common.RequsetJSON(req, 0)
+	req, err := http.NewRequestWithContext(ctx, "POST", "https://api.weixin.qq.com/cgi-bin/tags/members/batchuntagging?access_token=", bytes.NewReader(d))
+	_, err = wx.RequsetJSON(req, 0)
 	if err != nil {
-		log.Println(err)
+		wx.log(common.LevelError, "UnBatchTags failed", common.F("error", err))
 		return 0
 	}
 	return 1
@@ -387,16 +447,21 @@ func (wx *Wechat) UnBatchTags(openid []string, tagid int) int {
 
 //UpdateRemark 设置备注名
 func (wx *Wechat) UpdateRemark(openid, remark string) int {
+	return wx.UpdateRemarkContext(context.Background(), openid, remark)
+}
+
+//UpdateRemarkContext 同UpdateRemark，使用ctx控制请求的取消和超时
+func (wx *Wechat) UpdateRemarkContext(ctx context.Context, openid, remark string) int {
 	type stRemark struct {
 		Openid string `json:"openid"`
 		Remark string `json:"remark"`
 	}
 	t := stRemark{openid, remark}
 	d, _ := json.Marshal(t)
-	req, err := http.NewRequest("POST", "https://api.weixin.qq.com/cgi-bin/user/info/updateremark?access_token="+wx.AccessToken, bytes.NewReader(d))
-	_, err = common.RequsetJSON(req, 0)
+	req, err := http.NewRequestWithContext(ctx, "POST", "https://api.weixin.qq.com/cgi-bin/user/info/updateremark?access_token=", bytes.NewReader(d))
+	_, err = wx.RequsetJSON(req, 0)
 	if err != nil {
-		log.Println(err)
+		wx.log(common.LevelError, "UpdateRemark failed", common.F("error", err))
 		return 0
 	}
 
@@ -0,0 +1,188 @@
+package wechattest
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+//defaultHandler path对应接口的默认实现，未实现的接口返回成功
+func (s *Server) defaultHandler(path string) http.HandlerFunc {
+	switch {
+	case isPayPath(path):
+		return s.servePay
+	case strings.HasPrefix(path, "/cgi-bin/component/"), strings.HasPrefix(path, "/sns/oauth2/component/"):
+		return s.serveComponent
+	case strings.HasPrefix(path, "/wxa/"):
+		return s.serveWxa
+	case strings.HasPrefix(path, "/cgi-bin/open/"):
+		return s.serveOpen
+	}
+	switch path {
+	case "/cgi-bin/token":
+		return s.serveToken
+	case "/cgi-bin/ticket/getticket":
+		return func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, map[string]interface{}{
+				"errcode": 0, "errmsg": "ok", "ticket": "JSAPI_TICKET", "expires_in": 7200})
+		}
+	case "/sns/oauth2/access_token":
+		return func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, map[string]interface{}{
+				"access_token": "USER_ACCESS_TOKEN", "expires_in": 7200, "refresh_token": "USER_REFRESH_TOKEN",
+				"openid": "OPENID", "scope": "snsapi_userinfo"})
+		}
+	case "/sns/userinfo", "/cgi-bin/user/info":
+		return func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, user(r.URL.Query().Get("openid")))
+		}
+	case "/cgi-bin/user/info/batchget":
+		return serveUserBatchget
+	case "/cgi-bin/user/get":
+		return func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, map[string]interface{}{
+				"total": 2, "count": 2, "next_openid": "OPENID2",
+				"data": map[string][]string{"openid": {"OPENID1", "OPENID2"}}})
+		}
+	case "/cgi-bin/tags/create":
+		return func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, map[string]interface{}{"tag": map[string]interface{}{"id": 100}})
+		}
+	case "/cgi-bin/tags/get":
+		return func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, map[string]interface{}{"tags": []interface{}{}})
+		}
+	case "/cgi-bin/menu/get":
+		return func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, map[string]interface{}{"menu": map[string]interface{}{"button": []interface{}{}}})
+		}
+	case "/cgi-bin/menu/addconditional":
+		return func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, map[string]interface{}{"menuid": 1})
+		}
+	case "/cgi-bin/media/upload", "/cgi-bin/material/add_material", "/cgi-bin/material/add_news",
+		"/cgi-bin/media/uploadimg":
+		return func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, map[string]interface{}{
+				"type": r.URL.Query().Get("type"), "media_id": "MEDIA_ID", "url": "http://mmbiz.qpic.cn/MEDIA_ID",
+				"created_at": 1})
+		}
+	case "/cgi-bin/media/get", "/cgi-bin/material/get_material":
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Disposition", `attachment; filename="MEDIA_ID.jpg"`)
+			w.Write([]byte("MEDIA"))
+		}
+	case "/cgi-bin/material/batchget_material":
+		return func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, map[string]interface{}{"total_count": 0, "item_count": 0, "item": []interface{}{}})
+		}
+	case "/cgi-bin/message/template/send":
+		return func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, map[string]interface{}{"errcode": 0, "errmsg": "ok", "msgid": 1})
+		}
+	case "/card/create":
+		return func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, map[string]interface{}{"errcode": 0, "errmsg": "ok", "card_id": "CARD_ID"})
+		}
+	case "/card/batchget":
+		return func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, map[string]interface{}{
+				"errcode": 0, "errmsg": "ok", "card_id_list": []string{"CARD_ID"}, "total_num": 1})
+		}
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]interface{}{"errcode": 0, "errmsg": "ok"})
+	}
+}
+
+//serveToken 发放access_token
+func (s *Server) serveToken(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("appid") == "" || q.Get("secret") == "" {
+		writeJSON(w, map[string]interface{}{"errcode": 41002, "errmsg": "appid missing"})
+		return
+	}
+	writeJSON(w, map[string]interface{}{"access_token": s.IssueToken(), "expires_in": 7200})
+}
+
+//serveComponent 第三方平台接口
+func (s *Server) serveComponent(w http.ResponseWriter, r *http.Request) {
+	var req map[string]interface{}
+	json.NewDecoder(r.Body).Decode(&req)
+	str := func(k string) string {
+		v, _ := req[k].(string)
+		return v
+	}
+
+	switch strings.TrimPrefix(r.URL.Path, "/cgi-bin/component/") {
+	case "api_component_token":
+		if str("component_verify_ticket") == "" {
+			writeJSON(w, map[string]interface{}{"errcode": 61006, "errmsg": "component ticket is invalid"})
+			return
+		}
+		writeJSON(w, map[string]interface{}{
+			"component_access_token": s.issueComponentToken(), "expires_in": 7200})
+	case "api_create_preauthcode":
+		writeJSON(w, map[string]interface{}{"pre_auth_code": "PRE_AUTH_CODE", "expires_in": 1800})
+	case "api_query_auth":
+		writeJSON(w, map[string]interface{}{"authorization_info": s.authorization(Appid)})
+	case "api_authorizer_token":
+		writeJSON(w, map[string]interface{}{
+			"authorizer_access_token": s.IssueToken(), "expires_in": 7200,
+			"authorizer_refresh_token": "REFRESH_" + str("authorizer_appid")})
+	case "api_get_authorizer_info":
+		info := s.authorization(str("authorizer_appid"))
+		delete(info, "authorizer_access_token")
+		writeJSON(w, map[string]interface{}{
+			"authorizer_info": map[string]interface{}{
+				"nick_name": "test", "user_name": "gh_000000000001", "principal_name": "test",
+				"service_type_info": map[string]int{"id": 2}, "verify_type_info": map[string]int{"id": 0}},
+			"authorization_info": info})
+	case "api_get_authorizer_list":
+		writeJSON(w, map[string]interface{}{"total_count": 0, "list": []interface{}{}})
+	case "api_get_authorizer_option":
+		writeJSON(w, map[string]interface{}{
+			"authorizer_appid": str("authorizer_appid"), "option_name": str("option_name"), "option_value": "1"})
+	case "/sns/oauth2/component/access_token":
+		writeJSON(w, map[string]interface{}{
+			"access_token": "USER_ACCESS_TOKEN", "expires_in": 7200, "refresh_token": "USER_REFRESH_TOKEN",
+			"openid": "OPENID", "scope": r.URL.Query().Get("scope")})
+	default:
+		writeJSON(w, map[string]interface{}{"errcode": 0, "errmsg": "ok"})
+	}
+}
+
+//authorization 授权信息，包含全部权限集
+func (s *Server) authorization(appid string) map[string]interface{} {
+	var funcInfo []interface{}
+	for _, id := range []int{1, 2, 3, 4, 5, 6, 7, 11, 17, 18, 19, 22, 23, 25, 30, 31, 33} {
+		funcInfo = append(funcInfo, map[string]interface{}{
+			"funcscope_category": map[string]int{"id": id}})
+	}
+	return map[string]interface{}{
+		"authorizer_appid":         appid,
+		"authorizer_access_token":  s.IssueToken(),
+		"expires_in":               7200,
+		"authorizer_refresh_token": "REFRESH_" + appid,
+		"func_info":                funcInfo,
+	}
+}
+
+func user(openid string) map[string]interface{} {
+	return map[string]interface{}{
+		"subscribe": 1, "openid": openid, "nickname": "user_" + openid, "sex": 1, "language": "zh_CN"}
+}
+
+func serveUserBatchget(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		UserList []struct {
+			Openid string `json:"openid"`
+		} `json:"user_list"`
+	}
+	json.NewDecoder(r.Body).Decode(&req)
+	var list []interface{}
+	for _, u := range req.UserList {
+		list = append(list, user(u.Openid))
+	}
+	writeJSON(w, map[string]interface{}{"user_info_list": list})
+}
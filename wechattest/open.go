@@ -0,0 +1,67 @@
+package wechattest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+//BindOpenAccount 设置appid已绑定的开放平台帐号
+func (s *Server) BindOpenAccount(appid, openAppid string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.open[appid] = openAppid
+}
+
+//OpenAccount appid绑定的开放平台帐号，未绑定时返回空
+func (s *Server) OpenAccount(appid string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.open[appid]
+}
+
+//serveOpen 开放平台帐号管理接口
+func (s *Server) serveOpen(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Appid     string `json:"appid"`
+		OpenAppid string `json:"open_appid"`
+	}
+	json.NewDecoder(r.Body).Decode(&req)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	bound := s.open[req.Appid]
+
+	switch strings.TrimPrefix(r.URL.Path, "/cgi-bin/open/") {
+	case "create":
+		if bound != "" {
+			writeJSON(w, map[string]interface{}{"errcode": 89000, "errmsg": "account has bound open"})
+			return
+		}
+		s.seq++
+		s.open[req.Appid] = fmt.Sprintf("wxopen%010d", s.seq)
+		writeJSON(w, map[string]interface{}{"errcode": 0, "errmsg": "ok", "open_appid": s.open[req.Appid]})
+	case "bind":
+		if bound != "" {
+			writeJSON(w, map[string]interface{}{"errcode": 89000, "errmsg": "account has bound open"})
+			return
+		}
+		s.open[req.Appid] = req.OpenAppid
+		writeJSON(w, map[string]interface{}{"errcode": 0, "errmsg": "ok"})
+	case "unbind":
+		if bound == "" || bound != req.OpenAppid {
+			writeJSON(w, map[string]interface{}{"errcode": 89001, "errmsg": "not same contractor"})
+			return
+		}
+		delete(s.open, req.Appid)
+		writeJSON(w, map[string]interface{}{"errcode": 0, "errmsg": "ok"})
+	case "get":
+		if bound == "" {
+			writeJSON(w, map[string]interface{}{"errcode": 89002, "errmsg": "open not exists"})
+			return
+		}
+		writeJSON(w, map[string]interface{}{"errcode": 0, "errmsg": "ok", "open_appid": bound})
+	default:
+		writeJSON(w, map[string]interface{}{"errcode": 0, "errmsg": "ok"})
+	}
+}
@@ -0,0 +1,82 @@
+package wechattest
+
+import (
+	"encoding/xml"
+	"net/http"
+	"strings"
+
+	"github.com/wei193/component/common"
+	"github.com/wei193/component/wechat"
+)
+
+//isPayPath 是否为微信支付接口
+func isPayPath(path string) bool {
+	return strings.HasPrefix(path, "/pay/") || strings.HasPrefix(path, "/secapi/") ||
+		strings.HasPrefix(path, "/mmpaymkttransfers/")
+}
+
+//payRequest 支付请求的公共字段
+type payRequest struct {
+	Appid         string `xml:"appid"`
+	Mchid         string `xml:"mch_id"`
+	OutTradeNo    string `xml:"out_trade_no"`
+	Transactionid string `xml:"transaction_id"`
+	OutRefundNo   string `xml:"out_refund_no"`
+	TotalFee      int    `xml:"total_fee"`
+	RefundFee     int    `xml:"refund_fee"`
+	TradeType     string `xml:"trade_type"`
+	MchBillno     string `xml:"mch_billno"`
+	ReOpenid      string `xml:"re_openid"`
+	Wxappid       string `xml:"wxappid"`
+}
+
+//servePay 支付接口，响应使用PayKey签名
+func (s *Server) servePay(w http.ResponseWriter, r *http.Request) {
+	var req payRequest
+	if err := xml.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeFault(w, r, Fault{Errmsg: "XML格式错误"})
+		return
+	}
+	const nonce = "5K8264ILTKCH16CQ2502SI8ZNMTM67VS"
+
+	switch r.URL.Path {
+	case "/pay/unifiedorder":
+		res := wechat.ResUnifiedOrder{ReturnCode: "SUCCESS", ResultCode: "SUCCESS", Appid: req.Appid,
+			Mchid: req.Mchid, NonceStr: nonce, TradeType: req.TradeType, Prepayid: "wx_prepay_" + req.OutTradeNo}
+		res.Sign = common.XMLSignMd5(res, PayKey)
+		writeXML(w, res)
+	case "/pay/orderquery":
+		res := wechat.ResQueryOrder{ReturnCode: "SUCCESS", ResultCode: "SUCCESS", Appid: req.Appid,
+			Mchid: req.Mchid, NonceStr: nonce, TradeState: "SUCCESS", OutTradeNo: req.OutTradeNo,
+			Transactionid: req.Transactionid, TotalFee: 1}
+		if res.Transactionid == "" {
+			res.Transactionid = "4200000000" + req.OutTradeNo
+		}
+		res.Sign = common.XMLSignMd5(res, PayKey)
+		writeXML(w, res)
+	case "/pay/closeorder":
+		res := wechat.ResCloseOrder{ReturnCode: "SUCCESS", ResultCode: "SUCCESS", Appid: req.Appid,
+			Mchid: req.Mchid, NonceStr: nonce}
+		res.Sign = common.XMLSignMd5(res, PayKey)
+		writeXML(w, res)
+	case "/secapi/pay/refund":
+		res := wechat.ResRefund{ReturnCode: "SUCCESS", ResultCode: "SUCCESS", Appid: req.Appid,
+			Mchid: req.Mchid, NonceStr: nonce, OutTradeNo: req.OutTradeNo, Transactionid: req.Transactionid,
+			OutRefundNo: req.OutRefundNo, RefundID: "5000000000" + req.OutRefundNo, TotalFee: req.TotalFee,
+			RefundFee: req.RefundFee, CashFee: req.TotalFee}
+		res.Sign = common.XMLSignMd5(res, PayKey)
+		writeXML(w, res)
+	case "/pay/refundquery":
+		res := wechat.ResReqRefundquery{ReturnCode: "SUCCESS", ResultCode: "SUCCESS", Appid: req.Appid, Mchid: req.Mchid,
+			NonceStr: nonce, OutTradeNo: req.OutTradeNo, Transactionid: req.Transactionid}
+		res.Sign = common.XMLSignMd5(res, PayKey)
+		writeXML(w, res)
+	case "/pay/downloadbill":
+		w.Write([]byte("交易时间,公众账号ID,商户号\n"))
+	case "/mmpaymkttransfers/sendredpack":
+		writeXML(w, wechat.ResHongbao{ReturnCode: "SUCCESS", ResultCode: "SUCCESS", MchBillno: req.MchBillno,
+			Mchid: req.Mchid, Wxappid: req.Wxappid, ReOpenid: req.ReOpenid, SendListid: "SEND_LISTID"})
+	default:
+		writeFault(w, r, Fault{Errmsg: "接口不存在"})
+	}
+}
@@ -0,0 +1,277 @@
+// Copyright 2020 wei_193 Author. All Rights Reserved.
+//
+// 用于测试的微信接口模拟服务
+
+package wechattest
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+
+	"github.com/wei193/component"
+	"github.com/wei193/component/common"
+	"github.com/wei193/component/wechat"
+)
+
+//测试用的默认参数
+const (
+	Appid          = "wx0000000000000001"
+	Appsecret      = "test-appsecret"
+	ComponentAppid = "wx0000000000000002"
+	ComponentToken = "test-token"
+	//ComponentAesKey 43位EncodingAESKey
+	ComponentAesKey = "abcdefghijklmnopqrstuvwxyz0123456789ABCDEFG"
+	VerifyTicket    = "test-verify-ticket"
+	MchID           = "1000000001"
+	PayKey          = "0123456789abcdef0123456789abcdef"
+)
+
+//Fault 注入的错误响应
+type Fault struct {
+	//Status HTTP状态码，不为0且不为200时只返回该状态码
+	Status int
+	//Errcode 微信错误码
+	Errcode int
+	//Errmsg 错误信息
+	Errmsg string
+}
+
+//Request 服务收到的请求记录
+type Request struct {
+	Method string
+	Path   string
+	Query  map[string]string
+	Body   []byte
+}
+
+//Server 模拟微信接口的测试服务
+//默认实现令牌、第三方平台、用户、菜单、素材、模板消息、卡券和支付接口，
+//可通过Handle和SetResponse替换接口响应，通过Inject注入错误
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	seq      int
+	tokens   map[string]bool
+	compTok  string
+	handlers map[string]http.HandlerFunc
+	faults   map[string][]Fault
+	requests []Request
+	//open 授权方appid绑定的开放平台帐号
+	open map[string]string
+}
+
+//NewServer 启动模拟服务，使用完毕后调用Close关闭
+func NewServer() *Server {
+	s := &Server{
+		tokens:   make(map[string]bool),
+		handlers: make(map[string]http.HandlerFunc),
+		faults:   make(map[string][]Fault),
+		open:     make(map[string]string),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+//Client 连接到模拟服务的请求客户端，不重试系统繁忙错误
+func (s *Server) Client() *common.Client {
+	c := common.NewClient(s.Server.Client(), s.URL)
+	c.Retry = common.RetryPolicy{}
+	return c
+}
+
+//Wechat 创建连接到模拟服务的公众号，商户号为MchID，支付密钥为PayKey
+//退款等需要证书的接口仍需调用SetMch设置证书
+func (s *Server) Wechat(appid, appsecret string) *wechat.Wechat {
+	wx := wechat.New(appid, appsecret, ComponentToken, ComponentAesKey, "")
+	wx.Client = s.Client()
+	wx.Mch = &wechat.MchInfo{MchID: MchID, PayKey: PayKey}
+	return wx
+}
+
+//Component 创建连接到模拟服务的第三方平台，已设置component_verify_ticket
+func (s *Server) Component(appid, appsecret string) (*component.Component, error) {
+	c, err := component.NewComponent(appid, appsecret, ComponentToken, ComponentAesKey, VerifyTicket, "", 0)
+	if err != nil {
+		return nil, err
+	}
+	c.Client = s.Client()
+	return c, nil
+}
+
+//Handle 替换path对应接口的处理函数
+func (s *Server) Handle(path string, h http.HandlerFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[path] = h
+}
+
+//SetResponse 固定path对应接口的响应，body为[]byte或string时原样返回，否则编码为JSON
+func (s *Server) SetResponse(path string, body interface{}) {
+	var data []byte
+	switch v := body.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		data, _ = json.Marshal(v)
+	}
+	s.Handle(path, func(w http.ResponseWriter, r *http.Request) {
+		w.Write(data)
+	})
+}
+
+//Inject 为path对应接口注入错误，之后的请求依次返回faults，用完后恢复正常
+func (s *Server) Inject(path string, faults ...Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults[path] = append(s.faults[path], faults...)
+}
+
+//ExpireTokens 使已发放的access_token和component_access_token失效，
+//之后使用旧令牌的请求返回40001
+func (s *Server) ExpireTokens() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens = make(map[string]bool)
+	s.compTok = ""
+}
+
+//IssueToken 发放一个有效的access_token，用于直接设置到Wechat或Authorizer
+func (s *Server) IssueToken() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.issueToken("ACCESS_TOKEN")
+}
+
+//Requests 已收到的请求
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+//Calls path对应接口收到的请求次数
+func (s *Server) Calls(path string) int {
+	n := 0
+	for _, r := range s.Requests() {
+		if r.Path == path {
+			n++
+		}
+	}
+	return n
+}
+
+//issueToken 生成令牌，调用时需持有s.mu
+func (s *Server) issueToken(prefix string) string {
+	s.seq++
+	token := fmt.Sprintf("%s_%d", prefix, s.seq)
+	s.tokens[token] = true
+	return token
+}
+
+//issueComponentToken 生成component_access_token
+func (s *Server) issueComponentToken() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seq++
+	s.compTok = fmt.Sprintf("COMPONENT_ACCESS_TOKEN_%d", s.seq)
+	return s.compTok
+}
+
+//templatePaths 使用access_token参数传递component_access_token的代码模板库接口
+var templatePaths = map[string]bool{
+	"/wxa/gettemplatedraftlist": true,
+	"/wxa/addtotemplate":        true,
+	"/wxa/gettemplatelist":      true,
+	"/wxa/deletetemplate":       true,
+}
+
+//checkToken 检查请求中的令牌
+func (s *Server) checkToken(r *http.Request) bool {
+	q := r.URL.Query()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if t, ok := q["component_access_token"]; ok {
+		return s.compTok != "" && t[0] == s.compTok
+	}
+	if t, ok := q["access_token"]; ok {
+		if templatePaths[r.URL.Path] {
+			return s.compTok != "" && t[0] == s.compTok
+		}
+		return s.tokens[t[0]]
+	}
+	return true
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	r.Body = ioutil.NopCloser(strings.NewReader(string(body)))
+	query := make(map[string]string)
+	for k, v := range r.URL.Query() {
+		query[k] = v[0]
+	}
+
+	s.mu.Lock()
+	s.requests = append(s.requests, Request{Method: r.Method, Path: r.URL.Path, Query: query, Body: body})
+	var fault *Fault
+	if f := s.faults[r.URL.Path]; len(f) > 0 {
+		fault = &f[0]
+		s.faults[r.URL.Path] = f[1:]
+	}
+	h := s.handlers[r.URL.Path]
+	s.mu.Unlock()
+
+	if fault != nil {
+		writeFault(w, r, *fault)
+		return
+	}
+	if t, ok := r.URL.Query()["access_token"]; ok && t[0] == "" {
+		writeFault(w, r, Fault{Errcode: 41001, Errmsg: "access_token missing"})
+		return
+	}
+	if !s.checkToken(r) {
+		writeFault(w, r, Fault{Errcode: 40001, Errmsg: "invalid credential, access_token is invalid or not latest"})
+		return
+	}
+	if h == nil {
+		h = s.defaultHandler(r.URL.Path)
+	}
+	h(w, r)
+}
+
+//writeFault 返回注入的错误，支付接口返回XML
+func writeFault(w http.ResponseWriter, r *http.Request, f Fault) {
+	if f.Status != 0 && f.Status != http.StatusOK {
+		w.WriteHeader(f.Status)
+		return
+	}
+	if isPayPath(r.URL.Path) {
+		type st struct {
+			XMLName    xml.Name `xml:"xml"`
+			ReturnCode string   `xml:"return_code"`
+			ReturnMsg  string   `xml:"return_msg"`
+		}
+		writeXML(w, st{ReturnCode: "FAIL", ReturnMsg: f.Errmsg})
+		return
+	}
+	writeJSON(w, map[string]interface{}{"errcode": f.Errcode, "errmsg": f.Errmsg})
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(v)
+}
+
+//writeXML 以<xml>为根元素返回v
+func writeXML(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+	xml.NewEncoder(w).EncodeElement(v, xml.StartElement{Name: xml.Name{Local: "xml"}})
+}
@@ -0,0 +1,56 @@
+package wechattest
+
+import (
+	"testing"
+)
+
+func TestComponent(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+
+	c, err := srv.Component(ComponentAppid, Appsecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := c.QueryAuth("AUTH_CODE")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Appid != Appid || a.AuthorizerRefreshToken != "REFRESH_"+Appid {
+		t.Errorf("authorizer = %s %s", a.Appid, a.AuthorizerRefreshToken)
+	}
+
+	srv.ExpireTokens()
+	if _, err = a.GetMenu(); err != nil {
+		t.Fatal(err)
+	}
+	if n := srv.Calls("/cgi-bin/component/api_component_token"); n != 2 {
+		t.Errorf("component token requests = %d, want 2", n)
+	}
+	//首次刷新因component_access_token失效被拒绝，刷新后重放
+	if n := srv.Calls("/cgi-bin/component/api_authorizer_token"); n != 2 {
+		t.Errorf("authorizer token requests = %d, want 2", n)
+	}
+}
+
+func TestSetResponse(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+
+	wx := srv.Wechat(Appid, Appsecret)
+	wx.AccessToken = srv.IssueToken()
+	srv.SetResponse("/cgi-bin/tags/get", map[string]interface{}{
+		"tags": []map[string]interface{}{{"id": 2, "name": "星标组"}}})
+	tags, err := wx.GetTag()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tags) != 1 || tags[0].Tagname != "星标组" {
+		t.Errorf("tags = %+v", tags)
+	}
+
+	srv.Inject("/cgi-bin/tags/get", Fault{Status: 502})
+	if _, err = wx.GetTag(); err == nil {
+		t.Error("GetTag with injected 502 succeeded")
+	}
+}
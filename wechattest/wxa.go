@@ -0,0 +1,49 @@
+package wechattest
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+//QrcodeImage 体验版二维码接口返回的图片内容
+var QrcodeImage = []byte("\xff\xd8\xff\xe0QRCODE")
+
+//AuditID 提交审核接口返回的审核编号
+const AuditID = 1234567
+
+//serveWxa 小程序代码管理及代码模板库接口，审核状态默认为审核通过
+func (s *Server) serveWxa(w http.ResponseWriter, r *http.Request) {
+	switch strings.TrimPrefix(r.URL.Path, "/wxa/") {
+	case "get_qrcode":
+		w.Header().Set("Content-Type", "image/jpeg")
+		w.Write(QrcodeImage)
+	case "get_category":
+		writeJSON(w, map[string]interface{}{"errcode": 0, "errmsg": "ok", "category_list": []interface{}{
+			map[string]interface{}{"first_class": "工具", "second_class": "备忘录", "first_id": 1, "second_id": 2}}})
+	case "get_page":
+		writeJSON(w, map[string]interface{}{"errcode": 0, "errmsg": "ok", "page_list": []string{"pages/index/index"}})
+	case "submit_audit":
+		writeJSON(w, map[string]interface{}{"errcode": 0, "errmsg": "ok", "auditid": AuditID})
+	case "get_auditstatus":
+		var req struct {
+			Auditid int64 `json:"auditid"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Auditid != AuditID {
+			writeJSON(w, map[string]interface{}{"errcode": 85012, "errmsg": "invalid audit id"})
+			return
+		}
+		writeJSON(w, map[string]interface{}{"errcode": 0, "errmsg": "ok", "status": 0})
+	case "gettemplatedraftlist":
+		writeJSON(w, map[string]interface{}{"errcode": 0, "errmsg": "ok", "draft_list": []interface{}{
+			map[string]interface{}{"draft_id": 1, "create_time": 1488965944, "user_version": "v1.0.0",
+				"user_desc": "first", "source_miniprogram_appid": Appid}}})
+	case "gettemplatelist":
+		writeJSON(w, map[string]interface{}{"errcode": 0, "errmsg": "ok", "template_list": []interface{}{
+			map[string]interface{}{"template_id": 1, "draft_id": 1, "template_type": 0, "create_time": 1488965944,
+				"user_version": "v1.0.0", "user_desc": "first", "source_miniprogram_appid": Appid}}})
+	default:
+		writeJSON(w, map[string]interface{}{"errcode": 0, "errmsg": "ok"})
+	}
+}
@@ -0,0 +1,242 @@
+package component
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+
+	"github.com/wei193/component/common"
+)
+
+//JWxaCommit 上传小程序代码
+type JWxaCommit struct {
+	//TemplateID 代码库中的代码模板ID
+	TemplateID int64 `json:"template_id"`
+	//ExtJSON 第三方自定义的配置，json字符串
+	ExtJSON     string `json:"ext_json"`
+	UserVersion string `json:"user_version"`
+	UserDesc    string `json:"user_desc"`
+}
+
+//JWxaCategory 小程序可选类目
+type JWxaCategory struct {
+	FirstClass  string `json:"first_class"`
+	SecondClass string `json:"second_class"`
+	ThirdClass  string `json:"third_class,omitempty"`
+	FirstID     int    `json:"first_id"`
+	SecondID    int    `json:"second_id"`
+	ThirdID     int    `json:"third_id,omitempty"`
+}
+
+//JWxaAuditItem 提交审核的页面及类目
+type JWxaAuditItem struct {
+	Address     string `json:"address,omitempty"`
+	Tag         string `json:"tag,omitempty"`
+	FirstClass  string `json:"first_class,omitempty"`
+	SecondClass string `json:"second_class,omitempty"`
+	ThirdClass  string `json:"third_class,omitempty"`
+	FirstID     int    `json:"first_id,omitempty"`
+	SecondID    int    `json:"second_id,omitempty"`
+	ThirdID     int    `json:"third_id,omitempty"`
+	Title       string `json:"title,omitempty"`
+}
+
+//JWxaAudit 提交审核
+type JWxaAudit struct {
+	//ItemList 审核项，为空时由微信按已上传的代码自动填写
+	ItemList      []JWxaAuditItem `json:"item_list,omitempty"`
+	VersionDesc   string          `json:"version_desc,omitempty"`
+	FeedbackInfo  string          `json:"feedback_info,omitempty"`
+	FeedbackStuff string          `json:"feedback_stuff,omitempty"`
+}
+
+//WxaAuditStatus 审核状态
+type WxaAuditStatus int
+
+//审核状态
+const (
+	WxaAuditSuccess   WxaAuditStatus = 0 //审核成功
+	WxaAuditFail      WxaAuditStatus = 1 //审核被拒绝
+	WxaAuditing       WxaAuditStatus = 2 //审核中
+	WxaAuditWithdrawn WxaAuditStatus = 3 //已撤回
+	WxaAuditDelay     WxaAuditStatus = 4 //审核延后
+)
+
+//JWxaAuditStatus 审核状态
+type JWxaAuditStatus struct {
+	Auditid int64          `json:"auditid,omitempty"`
+	Status  WxaAuditStatus `json:"status"`
+	//Reason 被拒绝或延后的原因
+	Reason string `json:"reason"`
+	//ScreenShot 审核不通过的截图素材media_id，多个以|分隔
+	ScreenShot string `json:"screenshot"`
+}
+
+//Commit 为授权的小程序上传代码
+func (a *Authorizer) Commit(commit JWxaCommit) error {
+	return a.CommitContext(context.Background(), commit)
+}
+
+//CommitContext 同Commit，使用ctx控制请求的取消和超时
+func (a *Authorizer) CommitContext(ctx context.Context, commit JWxaCommit) error {
+	_, err := a.requsetWithToken(ctx, "https://api.weixin.qq.com/wxa/commit", "POST", nil, commit)
+	return err
+}
+
+//GetQrcode 获取体验版二维码图片，path为空时使用默认首页
+func (a *Authorizer) GetQrcode(path string) ([]byte, error) {
+	return a.GetQrcodeContext(context.Background(), path)
+}
+
+//GetQrcodeContext 同GetQrcode，使用ctx控制请求的取消和超时
+func (a *Authorizer) GetQrcodeContext(ctx context.Context, path string) ([]byte, error) {
+	accessToken, err := a.ValidAccessTokenContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	param := make(map[string]string)
+	if path != "" {
+		param["path"] = path
+	}
+	req, err := createRequset(ctx, "https://api.weixin.qq.com/wxa/get_qrcode?access_token="+url.QueryEscape(accessToken),
+		"GET", param, nil)
+	if err != nil {
+		return nil, err
+	}
+	if a.Guard != nil {
+		err = a.Guard(req)
+		if err != nil {
+			return nil, err
+		}
+	}
+	res, err := a.APIClient().Requset(req.WithContext(common.WithLogFields(ctx, common.F("appid", a.Appid))))
+	if err != nil {
+		return nil, err
+	}
+	//成功时返回图片，失败时返回json
+	if len(res) > 0 && res[0] == '{' {
+		err = common.CheckJSONError(req, res)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+//GetCategory 获取授权小程序帐号已设置的类目
+func (a *Authorizer) GetCategory() ([]JWxaCategory, error) {
+	return a.GetCategoryContext(context.Background())
+}
+
+//GetCategoryContext 同GetCategory，使用ctx控制请求的取消和超时
+func (a *Authorizer) GetCategoryContext(ctx context.Context) ([]JWxaCategory, error) {
+	res, err := a.requsetWithToken(ctx, "https://api.weixin.qq.com/wxa/get_category", "GET", nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	var data struct {
+		CategoryList []JWxaCategory `json:"category_list"`
+	}
+	err = json.Unmarshal(res, &data)
+	if err != nil {
+		return nil, err
+	}
+	return data.CategoryList, nil
+}
+
+//GetPage 获取已上传的代码的页面列表
+func (a *Authorizer) GetPage() ([]string, error) {
+	return a.GetPageContext(context.Background())
+}
+
+//GetPageContext 同GetPage，使用ctx控制请求的取消和超时
+func (a *Authorizer) GetPageContext(ctx context.Context) ([]string, error) {
+	res, err := a.requsetWithToken(ctx, "https://api.weixin.qq.com/wxa/get_page", "GET", nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	var data struct {
+		PageList []string `json:"page_list"`
+	}
+	err = json.Unmarshal(res, &data)
+	if err != nil {
+		return nil, err
+	}
+	return data.PageList, nil
+}
+
+//SubmitAudit 将已上传的代码提交审核，返回审核编号
+func (a *Authorizer) SubmitAudit(audit JWxaAudit) (auditid int64, err error) {
+	return a.SubmitAuditContext(context.Background(), audit)
+}
+
+//SubmitAuditContext 同SubmitAudit，使用ctx控制请求的取消和超时
+func (a *Authorizer) SubmitAuditContext(ctx context.Context, audit JWxaAudit) (auditid int64, err error) {
+	res, err := a.requsetWithToken(ctx, "https://api.weixin.qq.com/wxa/submit_audit", "POST", nil, audit)
+	if err != nil {
+		return 0, err
+	}
+	var data struct {
+		Auditid int64 `json:"auditid"`
+	}
+	err = json.Unmarshal(res, &data)
+	if err != nil {
+		return 0, err
+	}
+	return data.Auditid, nil
+}
+
+//GetAuditStatus 查询指定审核编号的审核状态
+func (a *Authorizer) GetAuditStatus(auditid int64) (status *JWxaAuditStatus, err error) {
+	return a.GetAuditStatusContext(context.Background(), auditid)
+}
+
+//GetAuditStatusContext 同GetAuditStatus，使用ctx控制请求的取消和超时
+func (a *Authorizer) GetAuditStatusContext(ctx context.Context, auditid int64) (status *JWxaAuditStatus, err error) {
+	type st struct {
+		Auditid int64 `json:"auditid"`
+	}
+	res, err := a.requsetWithToken(ctx, "https://api.weixin.qq.com/wxa/get_auditstatus", "POST", nil, st{auditid})
+	if err != nil {
+		return nil, err
+	}
+	status = &JWxaAuditStatus{Auditid: auditid}
+	err = json.Unmarshal(res, status)
+	if err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+//UndoCodeAudit 撤回审核中的代码
+func (a *Authorizer) UndoCodeAudit() error {
+	return a.UndoCodeAuditContext(context.Background())
+}
+
+//UndoCodeAuditContext 同UndoCodeAudit，使用ctx控制请求的取消和超时
+func (a *Authorizer) UndoCodeAuditContext(ctx context.Context) error {
+	_, err := a.requsetWithToken(ctx, "https://api.weixin.qq.com/wxa/undocodeaudit", "GET", nil, nil)
+	return err
+}
+
+//Release 发布已通过审核的代码
+func (a *Authorizer) Release() error {
+	return a.ReleaseContext(context.Background())
+}
+
+//ReleaseContext 同Release，使用ctx控制请求的取消和超时
+func (a *Authorizer) ReleaseContext(ctx context.Context) error {
+	_, err := a.requsetWithToken(ctx, "https://api.weixin.qq.com/wxa/release", "POST", nil, struct{}{})
+	return err
+}
+
+//RevertCodeRelease 回退到上一个线上版本
+func (a *Authorizer) RevertCodeRelease() error {
+	return a.RevertCodeReleaseContext(context.Background())
+}
+
+//RevertCodeReleaseContext 同RevertCodeRelease，使用ctx控制请求的取消和超时
+func (a *Authorizer) RevertCodeReleaseContext(ctx context.Context) error {
+	_, err := a.requsetWithToken(ctx, "https://api.weixin.qq.com/wxa/revertcoderelease", "GET", nil, nil)
+	return err
+}
@@ -0,0 +1,208 @@
+package component
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+)
+
+//DomainAction 域名操作类型
+type DomainAction string
+
+//域名操作类型
+const (
+	DomainAdd    DomainAction = "add"
+	DomainDelete DomainAction = "delete"
+	DomainSet    DomainAction = "set" //覆盖现有域名
+	DomainGet    DomainAction = "get"
+)
+
+//JWxaDomain 小程序服务器域名，域名需带协议头，如https://www.qq.com
+type JWxaDomain struct {
+	RequestDomain   []string `json:"requestdomain,omitempty"`
+	WsRequestDomain []string `json:"wsrequestdomain,omitempty"`
+	UploadDomain    []string `json:"uploaddomain,omitempty"`
+	DownloadDomain  []string `json:"downloaddomain,omitempty"`
+}
+
+//ModifyDomain 修改服务器域名，返回修改后的域名
+func (a *Authorizer) ModifyDomain(action DomainAction, domain JWxaDomain) (*JWxaDomain, error) {
+	return a.ModifyDomainContext(context.Background(), action, domain)
+}
+
+//ModifyDomainContext 同ModifyDomain，使用ctx控制请求的取消和超时
+func (a *Authorizer) ModifyDomainContext(ctx context.Context, action DomainAction, domain JWxaDomain) (*JWxaDomain, error) {
+	type st struct {
+		Action DomainAction `json:"action"`
+		JWxaDomain
+	}
+	res, err := a.requsetWithToken(ctx, "https://api.weixin.qq.com/wxa/modify_domain", "POST", nil, st{action, domain})
+	if err != nil {
+		return nil, err
+	}
+	data := new(JWxaDomain)
+	err = json.Unmarshal(res, data)
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+//GetDomain 获取服务器域名
+func (a *Authorizer) GetDomain() (*JWxaDomain, error) {
+	return a.GetDomainContext(context.Background())
+}
+
+//GetDomainContext 同GetDomain，使用ctx控制请求的取消和超时
+func (a *Authorizer) GetDomainContext(ctx context.Context) (*JWxaDomain, error) {
+	return a.ModifyDomainContext(ctx, DomainGet, JWxaDomain{})
+}
+
+//SetWebviewDomain 修改业务域名，返回修改后的业务域名
+func (a *Authorizer) SetWebviewDomain(action DomainAction, domains []string) ([]string, error) {
+	return a.SetWebviewDomainContext(context.Background(), action, domains)
+}
+
+//SetWebviewDomainContext 同SetWebviewDomain，使用ctx控制请求的取消和超时
+func (a *Authorizer) SetWebviewDomainContext(ctx context.Context, action DomainAction, domains []string) ([]string, error) {
+	type st struct {
+		Action        DomainAction `json:"action"`
+		WebviewDomain []string     `json:"webviewdomain,omitempty"`
+	}
+	res, err := a.requsetWithToken(ctx, "https://api.weixin.qq.com/wxa/setwebviewdomain", "POST", nil, st{action, domains})
+	if err != nil {
+		return nil, err
+	}
+	var data struct {
+		WebviewDomain []string `json:"webviewdomain"`
+	}
+	err = json.Unmarshal(res, &data)
+	if err != nil {
+		return nil, err
+	}
+	return data.WebviewDomain, nil
+}
+
+//GetWebviewDomain 获取业务域名
+func (a *Authorizer) GetWebviewDomain() ([]string, error) {
+	return a.GetWebviewDomainContext(context.Background())
+}
+
+//GetWebviewDomainContext 同GetWebviewDomain，使用ctx控制请求的取消和超时
+func (a *Authorizer) GetWebviewDomainContext(ctx context.Context) ([]string, error) {
+	return a.SetWebviewDomainContext(ctx, DomainGet, nil)
+}
+
+//WxaDomainSet 期望的域名配置，字段为nil时不调整该类域名，为空切片时删除该类全部域名
+type WxaDomainSet struct {
+	RequestDomain   []string
+	WsRequestDomain []string
+	UploadDomain    []string
+	DownloadDomain  []string
+	WebviewDomain   []string
+}
+
+//WxaDomainChange 调整的域名
+type WxaDomainChange struct {
+	Added          JWxaDomain
+	Deleted        JWxaDomain
+	WebviewAdded   []string
+	WebviewDeleted []string
+}
+
+//Empty 是否没有调整
+func (c *WxaDomainChange) Empty() bool {
+	return c.Added.empty() && c.Deleted.empty() && len(c.WebviewAdded) == 0 && len(c.WebviewDeleted) == 0
+}
+
+func (d JWxaDomain) empty() bool {
+	return len(d.RequestDomain) == 0 && len(d.WsRequestDomain) == 0 &&
+		len(d.UploadDomain) == 0 && len(d.DownloadDomain) == 0
+}
+
+//ReconcileDomain 对比期望的域名与当前域名，只删除多余的域名并添加缺少的域名
+//先添加后删除，添加被拒绝（如域名未备案）时不删除线上的域名
+func (a *Authorizer) ReconcileDomain(want WxaDomainSet) (change *WxaDomainChange, err error) {
+	return a.ReconcileDomainContext(context.Background(), want)
+}
+
+//ReconcileDomainContext 同ReconcileDomain，使用ctx控制请求的取消和超时
+func (a *Authorizer) ReconcileDomainContext(ctx context.Context, want WxaDomainSet) (change *WxaDomainChange, err error) {
+	change = new(WxaDomainChange)
+	if want.RequestDomain != nil || want.WsRequestDomain != nil ||
+		want.UploadDomain != nil || want.DownloadDomain != nil {
+		current, err := a.GetDomainContext(ctx)
+		if err != nil {
+			return change, err
+		}
+		change.Added.RequestDomain, change.Deleted.RequestDomain = diffDomain(want.RequestDomain, current.RequestDomain)
+		change.Added.WsRequestDomain, change.Deleted.WsRequestDomain = diffDomain(want.WsRequestDomain, current.WsRequestDomain)
+		change.Added.UploadDomain, change.Deleted.UploadDomain = diffDomain(want.UploadDomain, current.UploadDomain)
+		change.Added.DownloadDomain, change.Deleted.DownloadDomain = diffDomain(want.DownloadDomain, current.DownloadDomain)
+		if !change.Added.empty() {
+			_, err = a.ModifyDomainContext(ctx, DomainAdd, change.Added)
+			if err != nil {
+				return change, err
+			}
+		}
+		if !change.Deleted.empty() {
+			_, err = a.ModifyDomainContext(ctx, DomainDelete, change.Deleted)
+			if err != nil {
+				return change, err
+			}
+		}
+	}
+
+	if want.WebviewDomain != nil {
+		current, err := a.GetWebviewDomainContext(ctx)
+		if err != nil {
+			return change, err
+		}
+		change.WebviewAdded, change.WebviewDeleted = diffDomain(want.WebviewDomain, current)
+		if len(change.WebviewAdded) != 0 {
+			_, err = a.SetWebviewDomainContext(ctx, DomainAdd, change.WebviewAdded)
+			if err != nil {
+				return change, err
+			}
+		}
+		if len(change.WebviewDeleted) != 0 {
+			_, err = a.SetWebviewDomainContext(ctx, DomainDelete, change.WebviewDeleted)
+			if err != nil {
+				return change, err
+			}
+		}
+	}
+	return change, nil
+}
+
+//diffDomain 对比期望与当前的域名，want为nil时不调整
+//域名不区分大小写，忽略末尾的/
+func diffDomain(want, current []string) (add, del []string) {
+	if want == nil {
+		return nil, nil
+	}
+	wantSet := make(map[string]bool, len(want))
+	for _, d := range want {
+		wantSet[normalizeDomain(d)] = true
+	}
+	currentSet := make(map[string]bool, len(current))
+	for _, d := range current {
+		n := normalizeDomain(d)
+		currentSet[n] = true
+		if !wantSet[n] {
+			del = append(del, d)
+		}
+	}
+	for _, d := range want {
+		n := normalizeDomain(d)
+		if !currentSet[n] {
+			add = append(add, d)
+			currentSet[n] = true
+		}
+	}
+	return add, del
+}
+
+func normalizeDomain(d string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(d)), "/")
+}
@@ -0,0 +1,99 @@
+package component_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/wei193/component"
+	"github.com/wei193/component/wechattest"
+)
+
+func TestReconcileDomain(t *testing.T) {
+	srv := wechattest.NewServer()
+	defer srv.Close()
+	c, err := srv.Component(wechattest.ComponentAppid, wechattest.Appsecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := c.QueryAuth("AUTH_CODE")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type action struct {
+		Action string
+		Domain map[string][]string
+	}
+	var actions []action
+	rejectAdd := false
+	domains := map[string][]string{
+		"requestdomain":  {"https://old.example.com", "https://API.example.com/"},
+		"uploaddomain":   {"https://upload.example.com"},
+		"downloaddomain": {"https://download.example.com"},
+		"webviewdomain":  {"https://web.example.com"},
+	}
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&req)
+		a := action{Action: req["action"].(string), Domain: make(map[string][]string)}
+		for k, v := range req {
+			if list, ok := v.([]interface{}); ok {
+				for _, d := range list {
+					a.Domain[k] = append(a.Domain[k], d.(string))
+				}
+			}
+		}
+		if a.Action != "get" {
+			actions = append(actions, a)
+		}
+		if a.Action == "add" && rejectAdd {
+			w.Write([]byte(`{"errcode":85015,"errmsg":"domain rejected"}`))
+			return
+		}
+		resp := map[string]interface{}{"errcode": 0, "errmsg": "ok"}
+		for k, v := range domains {
+			resp[k] = v
+		}
+		json.NewEncoder(w).Encode(resp)
+	}
+	srv.Handle("/wxa/modify_domain", handler)
+	srv.Handle("/wxa/setwebviewdomain", handler)
+
+	change, err := a.ReconcileDomain(component.WxaDomainSet{
+		RequestDomain:  []string{"https://api.example.com", "https://new.example.com"},
+		UploadDomain:   []string{},
+		DownloadDomain: []string{"https://download.example.com"},
+		WebviewDomain:  []string{"https://web.example.com"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []action{
+		{"add", map[string][]string{"requestdomain": {"https://new.example.com"}}},
+		{"delete", map[string][]string{
+			"requestdomain": {"https://old.example.com"}, "uploaddomain": {"https://upload.example.com"}}},
+	}
+	if !reflect.DeepEqual(actions, want) {
+		t.Errorf("actions = %+v, want %+v", actions, want)
+	}
+	if len(change.WebviewAdded) != 0 || len(change.WebviewDeleted) != 0 || change.Empty() {
+		t.Errorf("change = %+v", change)
+	}
+
+	//添加被拒绝时不删除线上的域名
+	rejectAdd = true
+	for _, set := range []component.WxaDomainSet{
+		{RequestDomain: []string{"https://new.example.com"}},
+		{WebviewDomain: []string{"https://web2.example.com"}},
+	} {
+		actions = nil
+		if _, err = a.ReconcileDomain(set); err == nil {
+			t.Fatalf("ReconcileDomain(%+v) with rejected add succeeded", set)
+		}
+		if len(actions) != 1 || actions[0].Action != "add" {
+			t.Errorf("actions = %+v, want only the rejected add", actions)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package component
+
+import (
+	"context"
+	"time"
+
+	"github.com/wei193/component/common"
+	"github.com/wei193/component/wechat"
+)
+
+//小程序审核结果事件
+const (
+	EventWxaAuditSuccess = "weapp_audit_success"
+	EventWxaAuditFail    = "weapp_audit_fail"
+	EventWxaAuditDelay   = "weapp_audit_delay"
+)
+
+//ErrcodeWxaAlreadyReleased 代码已发布
+const ErrcodeWxaAlreadyReleased = 85052
+
+//WxaAuditResult 小程序审核结果
+type WxaAuditResult struct {
+	Appid  string
+	Status WxaAuditStatus
+	//Reason 被拒绝或延后的原因
+	Reason string
+	//ScreenShot 审核不通过的截图素材media_id，多个以|分隔
+	ScreenShot string
+	//Time 审核通过、被拒绝或延后的时间
+	Time int64
+	//Released 审核通过后已发布
+	Released bool
+}
+
+//ParseWxaAuditEvent 解析审核结果事件，不是审核结果事件时返回false
+func ParseWxaAuditEvent(req *wechat.STMsgRequest) (*WxaAuditResult, bool) {
+	if req == nil || req.MsgType != "event" {
+		return nil, false
+	}
+	result := &WxaAuditResult{
+		Appid:      req.ToUserName,
+		Reason:     req.Reason,
+		ScreenShot: req.ScreenShot,
+	}
+	switch req.Event {
+	case EventWxaAuditSuccess:
+		result.Status = WxaAuditSuccess
+		result.Time = req.SuccTime
+	case EventWxaAuditFail:
+		result.Status = WxaAuditFail
+		result.Time = req.FailTime
+	case EventWxaAuditDelay:
+		result.Status = WxaAuditDelay
+		result.Time = req.DelayTime
+	default:
+		return nil, false
+	}
+	return result, true
+}
+
+//Deploy 上传代码并提交审核，返回审核编号
+//审核结果通过WxaAuditHandler接收，或使用ReleaseAudited查询
+func (a *Authorizer) Deploy(commit JWxaCommit, audit JWxaAudit) (auditid int64, err error) {
+	return a.DeployContext(context.Background(), commit, audit)
+}
+
+//DeployContext 同Deploy，使用ctx控制请求的取消和超时
+func (a *Authorizer) DeployContext(ctx context.Context, commit JWxaCommit, audit JWxaAudit) (auditid int64, err error) {
+	err = a.CommitContext(ctx, commit)
+	if err != nil {
+		return 0, err
+	}
+	return a.SubmitAuditContext(ctx, audit)
+}
+
+//ReleaseAudited 查询审核状态，审核通过时发布代码
+func (a *Authorizer) ReleaseAudited(auditid int64) (result *WxaAuditResult, err error) {
+	return a.ReleaseAuditedContext(context.Background(), auditid)
+}
+
+//ReleaseAuditedContext 同ReleaseAudited，使用ctx控制请求的取消和超时
+func (a *Authorizer) ReleaseAuditedContext(ctx context.Context, auditid int64) (result *WxaAuditResult, err error) {
+	status, err := a.GetAuditStatusContext(ctx, auditid)
+	if err != nil {
+		return nil, err
+	}
+	result = &WxaAuditResult{
+		Appid:      a.Appid,
+		Status:     status.Status,
+		Reason:     status.Reason,
+		ScreenShot: status.ScreenShot,
+	}
+	if status.Status != WxaAuditSuccess {
+		return result, nil
+	}
+	err = a.releaseAudited(ctx)
+	if err != nil {
+		return result, err
+	}
+	result.Released = true
+	return result, nil
+}
+
+//releaseAudited 发布已通过审核的代码，代码已发布时视为成功
+func (a *Authorizer) releaseAudited(ctx context.Context) error {
+	err := a.ReleaseContext(ctx)
+	if e, ok := common.AsAPIError(err); ok && e.Errcode == ErrcodeWxaAlreadyReleased {
+		return nil
+	}
+	return err
+}
+
+//WxaAuditOptions 审核结果事件的处理选项
+type WxaAuditOptions struct {
+	//AutoRelease 审核通过时自动发布代码
+	AutoRelease bool
+	//ReleaseTimeout 自动发布的超时时间，为0时使用4秒，避免超过微信等待回复的5秒
+	ReleaseTimeout time.Duration
+	//OnResult 收到审核结果后调用，自动发布失败时err不为空
+	OnResult func(a *Authorizer, result *WxaAuditResult, err error)
+}
+
+//WxaAuditHandler 接收小程序审核结果事件的MsgHandler，开启AutoRelease时审核通过后发布代码
+//收到审核结果后调用opts.OnResult；其他消息交给next处理
+func WxaAuditHandler(next MsgHandler, opts WxaAuditOptions) MsgHandler {
+	timeout := opts.ReleaseTimeout
+	if timeout <= 0 {
+		timeout = 4 * time.Second
+	}
+	return func(a *Authorizer, req *wechat.STMsgRequest) (*wechat.STMsgResponse, error) {
+		result, ok := ParseWxaAuditEvent(req)
+		if !ok {
+			if next == nil {
+				return nil, nil
+			}
+			return next(a, req)
+		}
+		result.Appid = a.Appid
+		var err error
+		if result.Status == WxaAuditSuccess && opts.AutoRelease {
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			err = a.releaseAudited(ctx)
+			cancel()
+			result.Released = err == nil
+		}
+		if opts.OnResult != nil {
+			opts.OnResult(a, result, err)
+		}
+		return nil, nil
+	}
+}
@@ -0,0 +1,126 @@
+package component_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/wei193/component"
+	"github.com/wei193/component/wechat"
+	"github.com/wei193/component/wechattest"
+)
+
+func TestWxaDeploy(t *testing.T) {
+	srv := wechattest.NewServer()
+	defer srv.Close()
+	c, err := srv.Component(wechattest.ComponentAppid, wechattest.Appsecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := c.QueryAuth("AUTH_CODE")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	auditid, err := a.Deploy(component.JWxaCommit{TemplateID: 1, ExtJSON: `{"extEnable":true}`, UserVersion: "v1.0.0"},
+		component.JWxaAudit{VersionDesc: "first release"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if auditid != wechattest.AuditID {
+		t.Errorf("auditid = %d", auditid)
+	}
+	for _, r := range srv.Requests() {
+		if r.Path != "/wxa/commit" {
+			continue
+		}
+		var commit component.JWxaCommit
+		json.Unmarshal(r.Body, &commit)
+		if commit.TemplateID != 1 || commit.UserVersion != "v1.0.0" {
+			t.Errorf("commit = %+v", commit)
+		}
+	}
+
+	qrcode, err := a.GetQrcode("pages/index/index")
+	if err != nil || !bytes.Equal(qrcode, wechattest.QrcodeImage) {
+		t.Errorf("GetQrcode = %q, %v", qrcode, err)
+	}
+	srv.SetResponse("/wxa/get_qrcode", `{"errcode":85070,"errmsg":"qrcode not found"}`)
+	if _, err = a.GetQrcode(""); err == nil {
+		t.Error("GetQrcode with errcode succeeded")
+	}
+	categories, err := a.GetCategory()
+	if err != nil || len(categories) != 1 || categories[0].SecondID != 2 {
+		t.Errorf("GetCategory = %+v, %v", categories, err)
+	}
+
+	result, err := a.ReleaseAudited(auditid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Released || srv.Calls("/wxa/release") != 1 {
+		t.Errorf("result = %+v, release calls = %d", result, srv.Calls("/wxa/release"))
+	}
+	srv.SetResponse("/wxa/release", `{"errcode":85052,"errmsg":"app is already released"}`)
+	if result, err = a.ReleaseAudited(auditid); err != nil || !result.Released {
+		t.Errorf("ReleaseAudited when already released = %+v, %v", result, err)
+	}
+}
+
+func TestWxaAuditHandler(t *testing.T) {
+	srv := wechattest.NewServer()
+	defer srv.Close()
+	c, err := srv.Component(wechattest.ComponentAppid, wechattest.Appsecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := c.QueryAuth("AUTH_CODE")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var results []*component.WxaAuditResult
+	passed := 0
+	h := component.WxaAuditHandler(func(a *component.Authorizer, req *wechat.STMsgRequest) (*wechat.STMsgResponse, error) {
+		passed++
+		return nil, nil
+	}, component.WxaAuditOptions{AutoRelease: true, OnResult: func(a *component.Authorizer, result *component.WxaAuditResult, err error) {
+		if err != nil {
+			t.Error(err)
+		}
+		results = append(results, result)
+	}})
+
+	h(a, &wechat.STMsgRequest{MsgType: "event", Event: component.EventWxaAuditFail, Reason: "bad", FailTime: 1})
+	h(a, &wechat.STMsgRequest{MsgType: "event", Event: component.EventWxaAuditSuccess, SuccTime: 2})
+	h(a, &wechat.STMsgRequest{MsgType: "text", Content: "hello"})
+	//重复推送时代码已发布视为成功
+	srv.SetResponse("/wxa/release", `{"errcode":85052,"errmsg":"app is already released"}`)
+	h(a, &wechat.STMsgRequest{MsgType: "event", Event: component.EventWxaAuditSuccess, SuccTime: 3})
+
+	if len(results) != 3 || passed != 1 {
+		t.Fatalf("results = %d, passed = %d", len(results), passed)
+	}
+	if r := results[2]; !r.Released {
+		t.Errorf("already released result = %+v", r)
+	}
+	if r := results[0]; r.Status != component.WxaAuditFail || r.Reason != "bad" || r.Released {
+		t.Errorf("fail result = %+v", r)
+	}
+	if r := results[1]; r.Status != component.WxaAuditSuccess || r.Time != 2 || !r.Released {
+		t.Errorf("success result = %+v", r)
+	}
+	if n := srv.Calls("/wxa/release"); n != 2 {
+		t.Errorf("release calls = %d, want 2", n)
+	}
+
+	//未开启AutoRelease时不发布
+	results = nil
+	h = component.WxaAuditHandler(nil, component.WxaAuditOptions{OnResult: func(a *component.Authorizer, result *component.WxaAuditResult, err error) {
+		results = append(results, result)
+	}})
+	h(a, &wechat.STMsgRequest{MsgType: "event", Event: component.EventWxaAuditSuccess, SuccTime: 4})
+	if len(results) != 1 || results[0].Released || srv.Calls("/wxa/release") != 2 {
+		t.Errorf("results = %+v, release calls = %d", results, srv.Calls("/wxa/release"))
+	}
+}